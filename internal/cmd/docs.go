@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var docsDir string
+var docsFormat string
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "generate the microcode reference documentation",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opCodes := instructionSet.New(logging.New(func(bool) {}))
+		var files = map[string]func() string{}
+		switch docsFormat {
+		case "md":
+			files["microcode.md"] = opCodes.Markdown
+		case "html":
+			files["microcode.html"] = opCodes.HTML
+		case "all":
+			files["microcode.md"] = opCodes.Markdown
+			files["microcode.html"] = opCodes.HTML
+		default:
+			return fmt.Errorf("unknown format '%s', expected md, html or all", docsFormat)
+		}
+		if err := os.MkdirAll(docsDir, 0755); err != nil {
+			return err
+		}
+		for name, generate := range files {
+			filename := filepath.Join(docsDir, name)
+			if err := ioutil.WriteFile(filename, []byte(generate()), 0644); err != nil {
+				return err
+			}
+			fmt.Printf("%s written\n", filename)
+		}
+		return nil
+	},
+}
+
+func init() {
+	docsCmd.Flags().StringVarP(&docsDir,    "out",    "o", "docs", "directory to write the documentation to")
+	docsCmd.Flags().StringVarP(&docsFormat, "format", "f", "all",  "documentation format: md, html or all")
+	rootCmd.AddCommand(docsCmd)
+}
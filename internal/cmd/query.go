@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"strings"
+)
+
+var queryCmd = &cobra.Command{
+	Use:          "query <expression>",
+	Short:        "search the microcode, e.g. \"SPLD and phi2\" or \"AUIB and AUSA and not C\"",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         func(cmd *cobra.Command, args []string) error {
+		opCodes := instructionSet.New(logging.New(func(bool) {}))
+		matches, err := opCodes.Query(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			fmt.Println(m)
+		}
+		fmt.Printf("%d match(es)\n", len(matches))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+}
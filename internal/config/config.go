@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +18,11 @@ const (
 	defSerialStopBits  = 1
 	defMinimumReadSize = 8
 	defSerialParity    = 0
+	defSerialTimeoutMs = 5000
+	defSerialRetries   = 3
+
+	defClockHz         = 10
+	defClockRunCycles  = 100
 
 	defTerminalWidth   = 80
 	defTerminalHeight  = 50
@@ -24,12 +31,16 @@ const (
 )
 
 var CLIConfig *Config
+var configFile string
 var replacer = strings.NewReplacer(".", "_")
 
 type Config struct {
 	Terminal *Terminal `mapstructure:"terminal"`
 	Serial *Serial     `mapstructure:"serial"`
+	Clock *Clock       `mapstructure:"clock"`
 	RomFile string     `mapstructure:"rom_file"`
+	Interrupts []string `mapstructure:"interrupts"`
+	Keys map[string]map[string][]string `mapstructure:"keys"`
 }
 
 type Serial struct {
@@ -39,6 +50,15 @@ type Serial struct {
 	StopBits        int    `mapstructure:"stop_bits"`
 	Parity          int    `mapstructure:"parity"`
 	MinimumReadSize int    `mapstructure:"minimum_read_size"`
+	Framed          bool   `mapstructure:"framed"`
+	TimeoutMs       int    `mapstructure:"timeout_ms"`
+	Retries         int    `mapstructure:"retries"`
+	TrafficLog      string `mapstructure:"traffic_log"`
+}
+
+type Clock struct {
+	Hz        int `mapstructure:"hz"`
+	RunCycles int `mapstructure:"run_cycles"`
 }
 
 type Terminal struct {
@@ -55,6 +75,13 @@ func DefaultConfig () *Config {
 			StopBits:        defSerialStopBits,
 			Parity:          defSerialParity,
 			MinimumReadSize: defMinimumReadSize,
+			Framed:          false,
+			TimeoutMs:       defSerialTimeoutMs,
+			Retries:         defSerialRetries,
+		},
+		Clock: &Clock{
+			Hz:              defClockHz,
+			RunCycles:       defClockRunCycles,
 		},
 		Terminal: &Terminal{
 			Width:           defTerminalWidth,
@@ -66,6 +93,7 @@ func DefaultConfig () *Config {
 
 func NewConfig(cfgFile string) error {
 	v := viper.New()
+	configFile = cfgFile
 
 	CLIConfig = DefaultConfig()
 
@@ -126,4 +154,51 @@ func bindVars(v *viper.Viper, t reflect.Type, prefix string) {
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+// SavePortName records the selected serial port in the config file, rewriting only
+// the port_name line so that comments and layout are kept
+func SavePortName(name string) error {
+	if configFile == "" {
+		return fmt.Errorf("no config file in use")
+	}
+	bs, err := ioutil.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(configFile, []byte(setPortName(string(bs), name)), 0644)
+}
+
+// setPortName replaces the value of port_name in the top level serial block,
+// keeping any trailing comment, and adds the key or the block if it is missing
+func setPortName(text string, name string) string {
+	value := strconv.Quote(name)
+	lines := strings.Split(text, "\n")
+	serial, inSerial := -1, false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			if inSerial = strings.HasPrefix(trimmed, "serial:"); inSerial {
+				serial = i
+			}
+		} else if inSerial && strings.HasPrefix(trimmed, "port_name:") {
+			indent := line[:len(line) - len(strings.TrimLeft(line, " \t"))]
+			comment, rest := "", trimmed[len("port_name:"):]
+			if c := strings.Index(rest, " #"); c >= 0 {
+				comment = rest[len(strings.TrimRight(rest[:c], " \t")):]
+			}
+			lines[i] = indent + "port_name: " + value + comment
+			return strings.Join(lines, "\n")
+		}
+	}
+	if serial < 0 {
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		return text + "serial:\n  port_name: " + value + "\n"
+	}
+	lines = append(lines[:serial+1], append([]string{"  port_name: " + value}, lines[serial+1:]...)...)
+	return strings.Join(lines, "\n")
+}
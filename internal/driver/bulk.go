@@ -0,0 +1,203 @@
+package driver
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/undo"
+	"strings"
+)
+
+// Edit scopes, cycled with 'S'. A line change made in the control line or bus
+// editor is applied to every cell in scope once the preview is confirmed.
+const (
+	scopeVariant = iota
+	scopeAllFlags
+	scopeFlagMask
+	scopeAddrMode
+	scopeCount
+)
+
+// CycleScope moves to the next edit scope. The flag mask scope starts as the flag
+// under the flags cursor with its value in the edited variant; :scope sets other masks.
+func (d *Driver) CycleScope() {
+	d.scope = (d.scope + 1) % scopeCount
+	if d.scope == scopeFlagMask {
+		d.scopeMask = d.flags.CursorBit()
+		d.scopeValue = d.editFlags() & d.scopeMask
+	}
+	d.log.Infof("Edit scope: %s", d.scopeName())
+	d.redraw(false)
+}
+
+// scopeFlagNames are the flags a mask scope can test, from bit 3 down
+var scopeFlagNames = []string{"N", "V", "Z", "C"}
+
+// setScopeMask reads flag values such as N=1 Z=0 into the flag mask scope
+func (d *Driver) setScopeMask(args []string) error {
+	var mask, value uint8
+	for _, arg := range args {
+		parts := strings.SplitN(strings.ToUpper(arg), "=", 2)
+		bit := uint8(0)
+		for i, name := range scopeFlagNames {
+			if parts[0] == name {
+				bit = 8 >> i
+			}
+		}
+		if bit == 0 || len(parts) != 2 || parts[1] != "0" && parts[1] != "1" {
+			return fmt.Errorf("expected a flag value such as N=1, not '%s'", arg)
+		}
+		mask |= bit
+		if parts[1] == "1" {
+			value |= bit
+		}
+	}
+	if mask == 0 {
+		return fmt.Errorf("usage: scope mask <N|V|Z|C>=<0|1> ...")
+	}
+	d.scope, d.scopeMask, d.scopeValue = scopeFlagMask, mask, value
+	return nil
+}
+func (d *Driver) scopeName() string {
+	switch d.scope {
+	case scopeAllFlags:
+		return "all flag variants"
+	case scopeFlagMask:
+		var names []string
+		for i, name := range scopeFlagNames {
+			if bit := uint8(8 >> i); d.scopeMask & bit != 0 {
+				names = append(names, fmt.Sprintf("%s=%d", name, d.scopeValue & bit / bit))
+			}
+		}
+		return "variants with " + strings.Join(names, " ")
+	case scopeAddrMode:
+		return "all " + instructionSet.AddressModeNames[d.editOpCode().AddrMode] + " opcodes"
+	}
+	return "this variant"
+}
+
+// editFlags are the flags of the variant shown in the control line editor
+func (d *Driver) editFlags() uint8 {
+	if d.flags.Ignore {
+		return d.flags.DevFlags()
+	}
+	return d.flags.CurrentFlags()
+}
+
+// cell is one control word affected by a bulk edit or paste
+type cell struct {
+	op     *instructionSet.OpCode
+	flags  uint8
+	step   uint8
+	clock  uint8
+	before uint64
+	after  uint64
+}
+
+// bulkCells works out the change made to the edit cell and repeats it for every cell in scope.
+// Bits that changed are copied, except that a reset to preset uses each cell's own preset.
+func (d *Driver) bulkCells(step uint8, clock uint8, before uint64, after uint64, command uint8) []cell {
+	changed := before ^ after
+	edit := d.editOpCode()
+	ops := []*instructionSet.OpCode{edit}
+	if d.scope == scopeAddrMode {
+		// Virtual opcodes share address modes with real ones, so only go with their own kind
+		for _, oc := range d.opCodes.Documented() {
+			if oc != edit && oc.AddrMode == edit.AddrMode && oc.Virtual == edit.Virtual {
+				ops = append(ops, oc)
+			}
+		}
+	}
+	var cells []cell
+	for _, op := range ops {
+		for flags := uint8(0); flags < 16; flags++ {
+			if d.scope == scopeFlagMask && flags & d.scopeMask != d.scopeValue || step >= op.StepCount(flags) {
+				continue
+			}
+			word := op.Lines[flags][step][clock]
+			next := word &^ changed | after & changed
+			if command == 2 {
+				next = word &^ changed | op.Presets[flags][step][clock] & changed
+			}
+			if next != word {
+				cells = append(cells, cell{op: op, flags: flags, step: step, clock: clock, before: word, after: next})
+			}
+		}
+	}
+	return cells
+}
+
+// bulkPreview lists the cells a scoped edit will change, applying them when confirmed
+type bulkPreview struct {
+	d      *Driver
+	step   uint8
+	clock  uint8
+	cells  []cell
+	scope  string
+	list   common.List
+}
+
+func (d *Driver) previewBulk(step uint8, clock uint8, cells []cell) {
+	if len(cells) == 0 {
+		d.log.Info("No cells in scope would change")
+		return
+	}
+	d.UIs = append([]common.UI{&bulkPreview{d: d, step: step, clock: clock, cells: cells, scope: d.scopeName()}}, d.UIs...)
+	d.redraw(true)
+}
+
+func (b *bulkPreview) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	t.HideCursor()
+	b.list.Layout(t.Rows() - 4, len(b.cells))
+	t.PrintAtf(1, 1, "%sBulk edit of step %d Φ%d across %s: %d cell(s)%s%s", common.Yellow, b.step, b.clock + 1, b.scope, len(b.cells), common.Reset, display.ClearEnd)
+	for row := 0; row < b.list.Rows; row++ {
+		line := ""
+		if i, ok := b.list.Item(row); ok {
+			c := b.cells[i]
+			line = fmt.Sprintf("%s$%s %-3s %-3s flags %04b  %s%s", common.White, display.HexData(c.op.OpCode), c.op.Name, instructionSet.AddressModeNames[c.op.AddrMode], c.flags, instructionSet.DescribeChange(b.clock, c.before, c.after), common.Reset)
+		}
+		t.PrintAtf(1, row + 3, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, b.d.keys.Help(keymap.BulkEdit, "apply") + ", any other key to cancel")
+}
+func (b *bulkPreview) Process(input common.Input) bool {
+	switch action := b.d.keys.Action(keymap.BulkEdit, input); action {
+	case "up", "down", "page_up", "page_down":
+		b.list.Move(action)
+	case "apply":
+		b.d.applyCells(b.cells, fmt.Sprintf("Bulk edit step %d Φ%d across %s (%d cells)", b.step, b.clock + 1, b.scope, len(b.cells)))
+		return true
+	default:
+		b.d.log.Info("Bulk edit cancelled")
+		return true
+	}
+	b.d.redraw(true)
+	return false
+}
+
+// applyCells writes the cells and records them as a single undo entry
+func (d *Driver) applyCells(cells []cell, description string) {
+	set := func(after bool) {
+		for _, c := range cells {
+			if after {
+				c.op.Lines[c.flags][c.step][c.clock] = c.after
+			} else {
+				c.op.Lines[c.flags][c.step][c.clock] = c.before
+			}
+			d.sendLine(c.op, c.flags, c.step, c.clock)
+		}
+		d.redraw(true)
+	}
+	set(true)
+	d.undo.Push(undo.Action{
+		Description: description,
+		Undo:        func() { set(false) },
+		Redo:        func() { set(true) },
+	})
+	d.log.Info(description)
+}
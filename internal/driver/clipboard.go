@@ -0,0 +1,93 @@
+package driver
+
+import (
+	"fmt"
+	"github.com/atotto/clipboard"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+)
+
+// Lines a paste never changes, as they are only set by the editor itself
+const keepLines = instructionSet.CL_CTMR | instructionSet.CL_PAUS
+
+// MarkSteps starts a range of steps to copy at the edit step
+func (d *Driver) MarkSteps() {
+	d.copyMark = int(d.lines.EditStep()) - 1
+	d.log.Infof("Copy range starts at step %d Φ%d", d.copyMark / 2, d.copyMark % 2 + 1)
+}
+
+// CopySteps copies the phases from the mark to the edit step, or just the edit step
+// if there is no mark, to the clipboard
+func (d *Driver) CopySteps() {
+	from, to := int(d.lines.EditStep()) - 1, int(d.lines.EditStep()) - 1
+	if d.copyMark >= 0 {
+		from = d.copyMark
+		d.copyMark = -1
+	}
+	if from > to {
+		from, to = to, from
+	}
+	op, flags := d.editOpCode(), d.editFlags()
+	var steps []instructionSet.StepLines
+	for index := from; index <= to; index++ {
+		active := op.Lines[flags][index / 2][index % 2] ^ instructionSet.Defaults[index % 2]
+		steps = append(steps, instructionSet.StepLines{Index: index, Active: active &^ keepLines})
+	}
+	d.clipSteps = instructionSet.FormatSteps(steps)
+	if err := clipboard.WriteAll(d.clipSteps); err != nil {
+		d.log.Infof("%d phase(s) copied (clipboard unavailable: %v)", len(steps), err)
+	} else {
+		d.log.Infof("%d phase(s) copied to clipboard", len(steps))
+	}
+}
+
+// PasteSteps writes copied phases into the current opcode from the edit step. A bare
+// list of mnemonics sets the edit step through setLine, so follows the edit scope.
+func (d *Driver) PasteSteps() {
+	text, err := clipboard.ReadAll()
+	if err != nil || text == "" {
+		text = d.clipSteps
+	}
+	steps, err := instructionSet.ParseSteps(text)
+	if err != nil {
+		d.log.Warnf("Cannot paste: %v", err)
+		return
+	}
+	edit := int(d.lines.EditStep()) - 1
+	op, flags := d.editOpCode(), d.editFlags()
+	if len(steps) == 1 && steps[0].Index < 0 {
+		step, clock := uint8(edit / 2), uint8(edit % 2)
+		current := op.Lines[flags][step][clock]
+		word := instructionSet.Word(clock, steps[0].Active) &^ keepLines | current & keepLines
+		d.setLine(step, clock, word, 4)
+		return
+	}
+
+	base := steps[0].Index
+	if base % 2 != edit % 2 {
+		d.log.Warnf("Paste must start on Φ%d to match the copy", base % 2 + 1)
+		return
+	}
+	var cells []cell
+	for _, s := range steps {
+		target := edit + s.Index - base
+		if target < 0 || target >= int(op.StepCount(flags)) * 2 {
+			d.log.Warnf("Paste runs past the last step of %s", op.Name)
+			return
+		}
+		step, clock := uint8(target / 2), uint8(target % 2)
+		before := op.Lines[flags][step][clock]
+		after := instructionSet.Word(clock, s.Active) &^ keepLines | before & keepLines
+		if str, ok := op.ValidateWord(step, clock, before, after); !ok {
+			d.log.Warnf("Step %d Φ%d: %s", step, clock + 1, str)
+			return
+		}
+		if after != before {
+			cells = append(cells, cell{op: op, flags: flags, step: step, clock: clock, before: before, after: after})
+		}
+	}
+	if len(cells) == 0 {
+		d.log.Info("Paste makes no changes")
+		return
+	}
+	d.applyCells(cells, fmt.Sprintf("Paste %d phase(s) into %s flags %d from step %d Φ%d", len(steps), op.Name, flags, edit / 2, edit % 2 + 1))
+}
@@ -0,0 +1,176 @@
+package driver
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/interrupts"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/serial"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/status"
+)
+
+const maxClockHz = 100000
+
+// clockCommand sends a host clock command, recording the resulting mode in the clock panel
+func (d *Driver) clockCommand(op byte, arg uint32, mode int, cycles uint32) bool {
+	if !d.connected {
+		d.log.Warn("Not connected")
+		return false
+	} else if !d.serial.Supports('K') {
+		d.log.Warn("Firmware does not support host clock control")
+		return false
+	} else if !d.serial.Clock(op, arg) {
+		d.log.Warn("Clock command failed")
+		return false
+	}
+	d.clock.SetMode(mode, d.clockHz, cycles)
+	d.redraw(false)
+	return true
+}
+
+func (d *Driver) StepPhase() {
+	d.clockCommand(serial.ClockPhase, 1, status.ClockStepping, 0)
+}
+func (d *Driver) StepCycle() {
+	d.clockCommand(serial.ClockCycles, 1, status.ClockStepping, 0)
+}
+
+// StepInstruction advances a phase at a time until the next opcode is fetched.
+// Without host clock control it lets the board run to a temporary breakpoint instead.
+func (d *Driver) StepInstruction() {
+	if !d.serial.Supports('K') {
+		d.memory.BreakOnNextFetch()
+		d.resume()
+	} else if d.clockCommand(serial.ClockPhase, 1, status.ClockStepping, 0) {
+		d.untilFetch = true
+	}
+}
+func (d *Driver) RunCycles(cycles uint32) {
+	if cycles == 0 {
+		return
+	}
+	if d.clockCommand(serial.ClockCycles, cycles, status.ClockRunning, cycles) {
+		d.log.Infof("Running %d cycle(s)", cycles)
+	}
+}
+
+// ToggleRun starts the clock running at the configured speed, or pauses it if it is already running
+func (d *Driver) ToggleRun() {
+	d.untilFetch = false
+	if d.clock.Mode() == status.ClockRunning {
+		d.clockCommand(serial.ClockPause, 0, status.ClockPaused, 0)
+	} else {
+		d.clockCommand(serial.ClockRun, d.clockHz, status.ClockRunning, 0)
+	}
+}
+
+// SetClockHz changes the run speed, applying it straight away if the clock is running
+func (d *Driver) SetClockHz(hz uint32) {
+	if hz < 1 {
+		hz = 1
+	} else if hz > maxClockHz {
+		hz = maxClockHz
+	}
+	d.clockHz = hz
+	if d.clock.Mode() == status.ClockRunning {
+		d.clockCommand(serial.ClockRun, hz, status.ClockRunning, 0)
+	}
+	d.log.Infof("Clock speed %dHz", hz)
+}
+
+func (d *Driver) ClockHz() uint32 {
+	return d.clockHz
+}
+
+// continueStep is called at the end of each tick to carry on an instruction step
+func (d *Driver) continueStep() {
+	if !d.untilFetch {
+		return
+	}
+	if d.step.CurrentStep() == 0 && d.clock.CurrentState() == 0 {
+		d.untilFetch = false
+		d.log.Infof("Stepped to %s", display.HexAddress(d.instrAddr))
+		return
+	}
+	d.queueClock(serial.ClockPhase, 1, status.ClockStepping)
+}
+
+// clockRequest is a clock command raised during a tick
+type clockRequest struct {
+	op   byte
+	arg  uint32
+	mode int
+}
+
+// queueClock holds a clock command until the tick that raised it has finished its
+// own exchanges. The edge it causes is queued on clockChan rather than ignored.
+func (d *Driver) queueClock(op byte, arg uint32, mode int) {
+	d.pendingClock = &clockRequest{op: op, arg: arg, mode: mode}
+}
+
+// sendPendingClock sends a queued clock command. Called on the input goroutine
+// once tickFunc returns.
+func (d *Driver) sendPendingClock() {
+	if req := d.pendingClock; req != nil {
+		d.pendingClock = nil
+		d.clockCommand(req.op, req.arg, req.mode, 0)
+	}
+}
+
+// assertInterrupt drives an interrupt line on the board for the scheduler
+func (d *Driver) assertInterrupt(line interrupts.Line, asserted bool) bool {
+	if !d.connected {
+		d.log.Warnf("%s can only be driven on a board, and none is attached", line)
+		return false
+	} else if !d.serial.Supports('Q') {
+		d.log.Warn("Firmware does not support driving interrupt lines")
+		return false
+	}
+	return d.serial.Interrupt(byte(line), asserted)
+}
+
+func defaultClockHz() uint32 {
+	if hz := config.CLIConfig.Clock.Hz; hz > 0 {
+		return uint32(hz)
+	}
+	return 10
+}
+
+// resume releases a break or watch pause and lets the board run on to the next
+// breakpoint, restarting the host clock if it had been stopped
+func (d *Driver) resume() {
+	d.breakPause, d.watchPause = false, false
+	if !d.connected {
+		d.log.Warn("Not connected")
+		return
+	}
+	flags := d.flags.DevFlags()
+	if !d.flags.Ignore {
+		flags = d.flags.CurrentFlags()
+	}
+	d.serial.SetLines(d.opCode.Lines[flags][d.step.CurrentStep()][d.clock.CurrentState()], false)
+	if mode := d.clock.Mode(); d.serial.Supports('K') && mode != status.ClockBoard && mode != status.ClockRunning {
+		d.clockCommand(serial.ClockRun, d.clockHz, status.ClockRunning, 0)
+	}
+}
+
+// stopClock halts a running host clock when a breakpoint pauses the board
+func (d *Driver) stopClock() {
+	if d.clock.Mode() == status.ClockRunning {
+		d.untilFetch = false
+		d.queueClock(serial.ClockPause, 0, status.ClockPaused)
+	}
+}
+
+func (d *Driver) StepOver() {
+	d.memory.StepOver(d.instrAddr)
+	d.resume()
+}
+func (d *Driver) StepOut() {
+	d.memory.StepOut()
+	d.resume()
+}
+func (d *Driver) RunToCursor() {
+	if d.memory.RunTo(d.memory.CursorAddress()) {
+		d.resume()
+	}
+}
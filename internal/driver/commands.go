@@ -0,0 +1,424 @@
+package driver
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxHistory = 50
+
+// command is one of the ':' commands, with the usage shown by :help
+type command struct {
+	name  string
+	usage string
+	run   func(d *Driver, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"break",  "break <hex address> [if <condition>]", (*Driver).cmdBreak},
+		{"clock",  "clock <hz>", (*Driver).cmdClock},
+		{"export", "export", (*Driver).cmdExport},
+		{"goto",   "goto <hex address>", (*Driver).cmdGoto},
+		{"help",   "help", (*Driver).cmdHelp},
+		{"load",   "load <rom file>", (*Driver).cmdLoad},
+		{"opcode", "opcode [<$xx|name>]", (*Driver).cmdOpCode},
+		{"run",    "run <cycles>", (*Driver).cmdRun},
+		{"save",   "save", (*Driver).cmdSave},
+		{"scope",  "scope [variant|flags|mask <N|V|Z|C>=<0|1> ...|mode]", (*Driver).cmdScope},
+		{"set",    "set <$xx|name> <step> <phi1|phi2> [+CL_X] [-CL_Y]", (*Driver).cmdSet},
+	}
+}
+
+// OpenCommand starts a ':' command line on the bottom row
+func (d *Driver) OpenCommand() {
+	d.command = true
+	d.commandInput = ""
+	d.historyPos = len(d.history)
+	d.redraw(false)
+}
+
+// commandIntercept edits the command line, taking every key until it is run or cancelled
+func (d *Driver) commandIntercept(input common.Input) {
+	switch {
+	case input.KeyCode == display.CursorUp:
+		if d.historyPos > 0 {
+			d.historyPos--
+			d.commandInput = d.history[d.historyPos]
+		}
+	case input.KeyCode == display.CursorDown:
+		if d.historyPos < len(d.history) - 1 {
+			d.historyPos++
+			d.commandInput = d.history[d.historyPos]
+		} else {
+			d.historyPos = len(d.history)
+			d.commandInput = ""
+		}
+	case input.KeyCode != 0:
+	case input.Ascii == 27:
+		d.command = false
+	case input.Ascii == 127:
+		if len(d.commandInput) > 0 {
+			d.commandInput = d.commandInput[:len(d.commandInput)-1]
+		}
+	case input.Ascii == '\t':
+		d.complete()
+	case input.Ascii == 13:
+		d.command = false
+		d.runCommand(strings.TrimSpace(d.commandInput))
+	case input.Ascii >= 32 && input.Ascii < 127:
+		d.commandInput += string(rune(input.Ascii))
+	}
+	d.redraw(false)
+}
+
+func (d *Driver) runCommand(text string) {
+	if text == "" {
+		return
+	}
+	if len(d.history) == 0 || d.history[len(d.history)-1] != text {
+		d.history = append(d.history, text)
+		if len(d.history) > maxHistory {
+			d.history = d.history[1:]
+		}
+	}
+	fields := strings.Fields(text)
+	name := strings.ToLower(fields[0])
+	var matches []command
+	for _, c := range commands {
+		if c.name == name {
+			matches = []command{c}
+			break
+		} else if strings.HasPrefix(c.name, name) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		d.log.Warnf("Unknown command '%s'. Try :help", fields[0])
+	case 1:
+		if err := matches[0].run(d, fields[1:]); err != nil {
+			d.log.Warnf(":%s: %v", matches[0].name, err)
+		}
+	default:
+		d.log.Warnf("Ambiguous command '%s'", fields[0])
+	}
+}
+
+// complete extends the word at the end of the command line: command names first,
+// then control lines after + or -, file names for load, and opcode names
+func (d *Driver) complete() {
+	fields := strings.Fields(d.commandInput)
+	if len(fields) == 0 || strings.HasSuffix(d.commandInput, " ") {
+		fields = append(fields, "")
+	}
+	word := fields[len(fields)-1]
+	var candidates []string
+	switch {
+	case len(fields) == 1:
+		for _, c := range commands {
+			candidates = append(candidates, c.name + " ")
+		}
+	case strings.HasPrefix(word, "+") || strings.HasPrefix(word, "-"):
+		for _, mnemonic := range instructionSet.Mnemonics() {
+			candidates = append(candidates, word[:1] + "CL_" + mnemonic + " ")
+		}
+		if !strings.HasPrefix(strings.ToUpper(word[1:]), "CL_") {
+			word = word[:1] + "CL_" + word[1:]
+		}
+	case strings.HasPrefix("load", strings.ToLower(fields[0])) && len(fields) == 2:
+		paths, _ := filepath.Glob(word + "*")
+		for _, path := range paths {
+			if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+				path += string(filepath.Separator)
+			}
+			candidates = append(candidates, path)
+		}
+	case len(fields) == 2:
+		seen := map[string]bool{}
+		for op := 0; op < 256; op++ {
+			if oc := d.opCodes.Lookup(uint8(op)); oc != nil && !seen[oc.Name] {
+				seen[oc.Name] = true
+				candidates = append(candidates, oc.Name + " ")
+			}
+		}
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToUpper(candidate), strings.ToUpper(word)) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		d.display.Bell()
+		return
+	}
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(strings.ToUpper(match), strings.ToUpper(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(matches) > 1 && len(prefix) <= len(word) {
+		sort.Strings(matches)
+		if len(matches) > 12 {
+			matches = append(matches[:12], "...")
+		}
+		d.log.Info(strings.Join(matches, " "))
+		return
+	}
+	d.commandInput = d.commandInput[:len(d.commandInput) - len(fields[len(fields)-1])] + prefix
+}
+
+// commandLine is drawn on the bottom row, below the notifications, while a command is being typed
+func (d *Driver) commandLine() string {
+	text := ":" + d.commandInput
+	if width := d.display.Cols() - 1; len(text) > width {
+		text = text[len(text)-width:]
+	}
+	return fmt.Sprintf("%s%s%s%s", common.BrightWhite, text, common.Reset, display.ClearEnd)
+}
+func (d *Driver) positionCommand() {
+	col := len(d.commandInput) + 2
+	if col > d.display.Cols() {
+		col = d.display.Cols()
+	}
+	d.display.At(col, d.display.Rows())
+}
+
+func (d *Driver) cmdGoto(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: goto <hex address>")
+	}
+	address, err := expression.EvalAddressHex(strings.Join(args, " "), d.memory)
+	if err != nil {
+		return err
+	}
+	d.memory.Goto(address)
+	d.editor = 1
+	return nil
+}
+func (d *Driver) cmdBreak(args []string) error {
+	text := strings.Join(args, " ")
+	condition := ""
+	if i := strings.Index(strings.ToLower(text), " if "); i >= 0 {
+		text, condition = text[:i], strings.TrimSpace(text[i+4:])
+	}
+	if text == "" {
+		return fmt.Errorf("usage: break <hex address> [if <condition>]")
+	}
+	address, err := expression.EvalAddressHex(text, d.memory)
+	if err != nil {
+		return err
+	}
+	return d.memory.SetBreakPoint(address, condition)
+}
+func (d *Driver) cmdExport(args []string) error {
+	if err := d.opCodes.Export(); err != nil {
+		return err
+	}
+	d.log.Info("Export complete")
+	return nil
+}
+func (d *Driver) cmdSave(args []string) error {
+	if !d.opCodes.WriteInstructions() {
+		return fmt.Errorf("microcode not saved")
+	}
+	return nil
+}
+
+// cmdLoad replaces the ROM, putting the previous one back if the new one cannot be read
+func (d *Driver) cmdLoad(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: load <rom file>")
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+	if !d.memory.LoadRom(d.log, args[0]) {
+		if d.memory.LoadRom(d.log, config.CLIConfig.RomFile) {
+			d.undo.Clear()
+		}
+		return fmt.Errorf("%s not loaded", args[0])
+	}
+	config.CLIConfig.RomFile = args[0]
+	d.instrAddr = 0x0200
+	d.cycles = 0
+	d.profiler.Reset()
+	d.coverage.Reset()
+	d.undo.Clear()
+	d.log.Infof("Loaded %s", args[0])
+	return nil
+}
+// cmdOpCode opens an opcode in the control line editor, or with no argument
+// goes back to editing the opcode being executed
+func (d *Driver) cmdOpCode(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: opcode [<$xx|name>]")
+	}
+	if len(args) == 0 {
+		d.editOpCodeOf(nil)
+		d.log.Info("Editing the executing opcode")
+		return nil
+	}
+	oc, err := d.resolveOpCode(args[0])
+	if err != nil {
+		return err
+	}
+	d.editOpCodeOf(oc)
+	d.log.Infof("Editing $%s %s %s", display.HexData(oc.OpCode), oc.Name, instructionSet.AddressModeNames[oc.AddrMode])
+	return nil
+}
+
+// cmdScope picks the edit scope by name, rather than cycling to it
+func (d *Driver) cmdScope(args []string) error {
+	if len(args) == 0 {
+		d.log.Infof("Edit scope: %s", d.scopeName())
+		return nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "variant":
+		d.scope = scopeVariant
+	case "flags":
+		d.scope = scopeAllFlags
+	case "mask":
+		if err := d.setScopeMask(args[1:]); err != nil {
+			return err
+		}
+	case "mode":
+		d.scope = scopeAddrMode
+	default:
+		return fmt.Errorf("usage: scope [variant|flags|mask <N|V|Z|C>=<0|1> ...|mode]")
+	}
+	d.log.Infof("Edit scope: %s", d.scopeName())
+	return nil
+}
+
+// cmdSet changes one phase of an opcode's microcode for the flags shown in the editor.
+// Changes to the opcode being edited go through setLine, so follow the edit scope.
+func (d *Driver) cmdSet(args []string) error {
+	if len(args) < 4 {
+		return fmt.Errorf("usage: set <$xx|name> <step> <phi1|phi2> [+CL_X] [-CL_Y]")
+	}
+	oc, err := d.resolveOpCode(args[0])
+	if err != nil {
+		return err
+	}
+	step, err := strconv.ParseUint(args[1], 0, 8)
+	if err != nil || step >= uint64(oc.Steps) {
+		return fmt.Errorf("%s has steps 0 to %d", oc.Name, oc.Steps - 1)
+	}
+	var clock uint8
+	switch strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(args[2]), "phi"), "φ") {
+	case "1":
+		clock = instructionSet.PHI1
+	case "2":
+		clock = instructionSet.PHI2
+	default:
+		return fmt.Errorf("phase must be phi1 or phi2, not '%s'", args[2])
+	}
+
+	flags := d.editFlags()
+	before := oc.Lines[flags][step][clock]
+	active := before ^ instructionSet.Defaults[clock]
+	for _, arg := range args[3:] {
+		if len(arg) < 2 || arg[0] != '+' && arg[0] != '-' {
+			return fmt.Errorf("expected +CL_X or -CL_X, not '%s'", arg)
+		}
+		bit, ok := instructionSet.LineBit(arg[1:])
+		if !ok {
+			return fmt.Errorf("unknown control line '%s'", arg[1:])
+		}
+		if arg[0] == '+' {
+			active |= bit
+		} else {
+			active &^= bit
+		}
+	}
+	after := instructionSet.Word(clock, active)
+	if oc == d.editOpCode() {
+		d.setLine(uint8(step), clock, after, 4)
+		return nil
+	}
+	if str, ok := oc.ValidateWord(uint8(step), clock, before, after); !ok {
+		return fmt.Errorf("%s", str)
+	} else if after == before {
+		d.log.Info("No change")
+		return nil
+	}
+	d.applyCells([]cell{{op: oc, flags: flags, step: uint8(step), clock: clock, before: before, after: after}},
+		fmt.Sprintf("%s flags %d step %d Φ%d: %s", oc.Name, flags, step, clock + 1, instructionSet.DescribeChange(clock, before, after)))
+	return nil
+}
+func (d *Driver) cmdClock(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: clock <hz>")
+	}
+	hz, err := strconv.ParseUint(args[0], 0, 32)
+	if err != nil {
+		return fmt.Errorf("invalid speed '%s'", args[0])
+	}
+	d.SetClockHz(uint32(hz))
+	return nil
+}
+func (d *Driver) cmdRun(args []string) error {
+	cycles := uint64(config.CLIConfig.Clock.RunCycles)
+	if len(args) > 0 {
+		var err error
+		if cycles, err = strconv.ParseUint(args[0], 0, 32); err != nil {
+			return fmt.Errorf("invalid cycle count '%s'", args[0])
+		}
+	}
+	d.RunCycles(uint32(cycles))
+	return nil
+}
+func (d *Driver) cmdHelp(args []string) error {
+	for _, c := range commands {
+		d.log.Info(":" + c.usage)
+	}
+	return nil
+}
+
+// resolveOpCode reads $xx, or a name such as ADC. A name shared by several opcodes
+// picks the one with the address mode of the opcode being edited, if there is one.
+func (d *Driver) resolveOpCode(text string) (*instructionSet.OpCode, error) {
+	if strings.HasPrefix(text, "$") || strings.HasPrefix(strings.ToLower(text), "0x") {
+		value, err := expression.Eval(text, d)
+		if err != nil || value < 0 || value > 0xff {
+			return nil, fmt.Errorf("invalid opcode '%s'", text)
+		}
+		return d.opCodes.Lookup(uint8(value)), nil
+	}
+	var matches []*instructionSet.OpCode
+	for op := 0; op < 256; op++ {
+		if oc := d.opCodes.Lookup(uint8(op)); oc != nil && strings.EqualFold(oc.Name, text) {
+			if edit := d.editOpCode(); edit != nil && oc.AddrMode == edit.AddrMode {
+				return oc, nil
+			}
+			matches = append(matches, oc)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown opcode '%s'", text)
+	case 1:
+		return matches[0], nil
+	}
+	var codes []string
+	for _, oc := range matches {
+		codes = append(codes, "$" + display.HexData(oc.OpCode) + " " + instructionSet.AddressModeNames[oc.AddrMode])
+	}
+	return nil, fmt.Errorf("%s is one of %s", strings.ToUpper(text), strings.Join(codes, ", "))
+}
@@ -6,12 +6,21 @@ import (
 	"github.com/pkg/term"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/coverage"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/datapath"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/interrupts"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/memory"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/profiler"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/query"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/serial"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/status"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/trace"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/undo"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/waveform"
 	"os"
 	"strings"
 	"sync"
@@ -37,6 +46,7 @@ type Driver struct {
 	memory       *memory.Memory
 	step         *status.Steps
 	flags        *status.Flags
+	registers    *status.Registers
 	UIs          []common.UI
 	dispChan     chan bool
 	monitorChan  chan bool
@@ -49,6 +59,31 @@ type Driver struct {
 	editor       int
 	xTerm        *term.Term
 	cycles       uint64
+	undo         *undo.Stack
+	clockHz      uint32
+	interrupts   *interrupts.Scheduler
+	keys         *keymap.Keymap
+	profiler     *profiler.Profiler
+	coverage     *coverage.Coverage
+	recorder     *trace.Recorder
+	waveform     *waveform.Waveform
+	datapath     *datapath.Datapath
+	query        *query.Query
+	scope        int
+	scopeMask    uint8
+	scopeValue   uint8
+	copyMark     int
+	clipSteps    string
+	command      bool
+	commandInput string
+	history      []string
+	historyPos   int
+	untilFetch   bool
+	pendingClock *clockRequest
+	editOp       *instructionSet.OpCode
+	sentData     int
+	watchPause   bool
+	breakPause   bool
 }
 func New() *Driver {
 	d := Driver{}
@@ -67,7 +102,8 @@ func New() *Driver {
 	d.buses        = [7]uint64 {1, 6, 7, 7, 0, 3, 0}
 	d.UIs          = append(d.UIs, &d)
 	d.errorPage    = NewErrorPage()
-	d.helpPage     = NewHelpPage()
+	d.keys         = keymap.New(config.CLIConfig.Keys)
+	d.helpPage     = NewHelpPage(d.keys, d.redraw)
 	d.log          = logging.New(d.redraw)
 	d.step         = status.NewSteps(d.log)
 	d.opCodes      = instructionSet.New(d.log)
@@ -75,17 +111,32 @@ func New() *Driver {
 	d.irq          = status.NewIrq(d.log, d.redraw)
 	d.nmi          = status.NewNmi(d.log, d.redraw)
 	d.reset        = status.NewReset(d.log, d.redraw, d.reload)
-	d.flags        = status.NewFlags(d.log, d.display, d.redraw)
-	d.memory       = memory.New(d.log, d.opCodes, d.display, d.redraw)
-	d.lines        = instructionSet.NewControlLines(d.log, d.display, d.redraw, d.setLine)
-	d.serial       = serial.New(d.log, d.clock, d.irq, d.nmi, d.reset, d.flags, d.step, d.connectionStatus, d.wg)
+	d.flags        = status.NewFlags(d.log, d.display, d.redraw, d.keys)
+	d.registers    = status.NewRegisters(d.log)
+	d.undo         = undo.New(d.log)
+	d.clockHz      = defaultClockHz()
+	d.interrupts   = interrupts.New(d.log, d.assertInterrupt)
+	d.profiler     = profiler.New(d.log, d.redraw, d.keys)
+	d.memory       = memory.New(d.log, d.opCodes, d.display, d.redraw, d.undo, d.keys, func() bool { return d.serial.Supports('x') }, d.Lookup)
+	d.coverage     = coverage.New(d.log, d.opCodes, d.romInfo, d.redraw, d.keys)
+	d.recorder     = trace.New()
+	d.waveform     = waveform.New(d.log, d.redraw, d.currentOpCode, d.recorder, d.ClockHz, d.keys)
+	d.datapath     = datapath.New(d.datapathState, d.redraw, d.keys)
+	d.query        = query.New(d.log, d.opCodes, d.redraw, d.jumpTo)
+	d.lines        = instructionSet.NewControlLines(d.log, d.display, d.redraw, d.keys, d.setLine)
+	d.serial       = serial.New(d.log, d.clock, d.irq, d.nmi, d.reset, d.flags, d.step, d.connectionStatus, d.redraw, d.keys, d.wg)
 	d.keyIntercept = append(d.keyIntercept, d.lines, d.memory, d.lines.BusController())
 	d.editor       = 0
+	d.copyMark     = -1
+	d.sentData     = -1
 	d.dispChan     = make(chan bool)
 	d.monitorChan  = make(chan bool)
-	d.clockChan    = make(chan bool)
+	d.clockChan    = make(chan bool, 1)
 	d.resetChan    = make(chan bool)
 	d.inputChan    = make(chan common.Input)
+	for _, e := range d.keys.Errors() {
+		d.log.Warn(e)
+	}
 	return &d
 }
 
@@ -100,6 +151,12 @@ func (d *Driver) Run() {
 		os.Exit(1)
 	}
 
+	for _, schedule := range config.CLIConfig.Interrupts {
+		if err := d.interrupts.Add(schedule); err != nil {
+			d.log.Warnf("Invalid interrupt schedule '%s': %v", schedule, err)
+		}
+	}
+
 	d.opCode = d.opCodes.Lookup(0x02)
 	for len(d.UIs) > 0 {
 		if a, k, e := d.ReadChar(); e != nil {
@@ -147,6 +204,7 @@ func (d *Driver) input(wg *sync.WaitGroup) {
 		select {
 		case connected, ok = <-d.monitorChan:
 			d.connected = connected
+			d.sentData = -1
 			d.redraw(true)
 
 		case input, ok = <-d.inputChan:
@@ -156,14 +214,18 @@ func (d *Driver) input(wg *sync.WaitGroup) {
 			}
 		case phaseChange, ok = <- d.clockChan:
 			d.tickFunc(phaseChange)
+			d.sendPendingClock()
 
 		case _, ok = <- d.resetChan:
 			d.instrAddr = 0x0200
 			d.cycles = 0
+			d.profiler.Reset()
+			d.coverage.Reset()
 			if !d.memory.LoadRom(d.log, config.CLIConfig.RomFile) {
 				d.log.Dump()
 				os.Exit(1)
 			}
+			d.undo.Clear()
 		}
 	}
 	d.serial.Terminate()
@@ -223,6 +285,12 @@ func (d *Driver) ReadChar() (ascii int, keyCode int, err error) {
 			case 52: keyCode = 102 // Option+3
 			case 53: keyCode = 103 // Option+4
 		}
+	} else if numRead == 4 && bs[0] == 27 && bs[1] == 91 && bs[3] == 126 {
+		// Four-character sequence "ESC-[n~" for page up / down
+		switch bs[2] {
+			case 53: keyCode = 33 // Page up
+			case 54: keyCode = 34 // Page down
+		}
 	} else if numRead == 1 {
 		ascii = int(bs[0])
 	} else {
@@ -243,14 +311,21 @@ func (d *Driver) SetAddress(address uint16) {
 }
 func (d *Driver) setLine(step uint8, clock uint8, bit uint64, command uint8) {
 
+	op := d.editOpCode()
 	flags := d.flags.DevFlags()
 	if !d.flags.Ignore {
 		flags = d.flags.CurrentFlags()
 	}
 
 	mask := uint64(0)
+	before := op.Lines[flags][step][clock]
 	if command != 99 {
-		if str, ok := d.opCode.ValidateLine(step, clock, bit); !ok {
+		if command == 4 {
+			if str, ok := op.ValidateWord(step, clock, before, bit); !ok {
+				d.log.Warn(str)
+				return
+			}
+		} else if str, ok := op.ValidateLine(step, clock, bit); !ok {
 			d.log.Warn(str)
 			return
 		}
@@ -258,23 +333,86 @@ func (d *Driver) setLine(step uint8, clock uint8, bit uint64, command uint8) {
 		mask = uint64(1 << bit)
 		switch command {
 		case 0:
-			d.opCode.Lines[flags][step][clock] = d.opCode.Lines[flags][step][clock] &^ mask
+			op.Lines[flags][step][clock] = op.Lines[flags][step][clock] &^ mask
 		case 1:
-			d.opCode.Lines[flags][step][clock] = d.opCode.Lines[flags][step][clock] | mask
+			op.Lines[flags][step][clock] = op.Lines[flags][step][clock] | mask
 		case 2:
-			d.opCode.Lines[flags][step][clock] = d.opCode.Lines[flags][step][clock]&^mask | d.opCode.Presets[flags][step][clock]&mask
+			op.Lines[flags][step][clock] = op.Lines[flags][step][clock]&^mask | op.Presets[flags][step][clock]&mask
 		case 3:
-			d.opCode.Lines[flags][step][clock] = d.opCode.Lines[flags][step][clock] ^ mask
+			op.Lines[flags][step][clock] = op.Lines[flags][step][clock] ^ mask
 		case 4:
-			d.opCode.Lines[flags][step][clock] = bit
+			op.Lines[flags][step][clock] = bit
+		}
+		if after := op.Lines[flags][step][clock]; after != before && d.scope != scopeVariant {
+			op.Lines[flags][step][clock] = before
+			d.previewBulk(step, clock, d.bulkCells(step, clock, before, after, command))
+			return
+		} else if after != before {
+			d.undo.Push(undo.Action{
+				Description: fmt.Sprintf("%s flags %d step %d Φ%d: %s", op.Name, flags, step, clock + 1, instructionSet.DescribeChange(clock, before, after)),
+				Undo:        func() { d.restoreLine(op, flags, step, clock, before) },
+				Redo:        func() { d.restoreLine(op, flags, step, clock, after) },
+			})
 		}
 	}
 
-	if step == d.step.CurrentStep() && clock == d.clock.CurrentState() && d.connected {
-		d.serial.SetLines(d.opCode.Lines[flags][step][d.clock.CurrentState()], d.memory.HasBreakPoint(d.instrAddr))
-	}
+	d.sendLine(op, flags, step, clock)
+	d.redraw(true)
+}
+
+// restoreLine puts back a control word from the undo history, resending it if it is live on the board
+func (d *Driver) restoreLine(op *instructionSet.OpCode, flags uint8, step uint8, clock uint8, value uint64) {
+	op.Lines[flags][step][clock] = value
+	d.sendLine(op, flags, step, clock)
 	d.redraw(true)
 }
+func (d *Driver) sendLine(op *instructionSet.OpCode, flags uint8, step uint8, clock uint8) {
+	current := d.flags.DevFlags()
+	if !d.flags.Ignore {
+		current = d.flags.CurrentFlags()
+	}
+	if op == d.opCode && flags == current && step == d.step.CurrentStep() && clock == d.clock.CurrentState() && d.connected {
+		d.serial.SetLines(op.Lines[flags][step][clock], d.pauseRequested())
+	}
+}
+
+// pauseRequested reports whether CL_PAUS should be asserted for the current instruction
+func (d *Driver) pauseRequested() bool {
+	return d.watchPause || d.breakPause
+}
+
+// Lookup resolves the machine state used by breakpoint conditions, and by address
+// expressions after the memory editor's own names
+func (d *Driver) Lookup(name string) (int64, bool) {
+	switch strings.ToLower(name) {
+	case "cycles":
+		return int64(d.cycles), true
+	case "pc":
+		return int64(d.instrAddr), true
+	case "address":
+		return int64(d.address), true
+	case "step":
+		return int64(d.step.CurrentStep()), true
+	case "phase":
+		return int64(d.clock.CurrentState()), true
+	case "status":
+		return int64(d.flags.Status()), true
+	case "opcode":
+		if d.opCode != nil {
+			return int64(d.opCode.OpCode), true
+		}
+	}
+	if value, ok := d.registers.Register(name); ok {
+		return int64(value), true
+	}
+	if set, ok := d.flags.Flag(name); ok {
+		if set {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
 
 func (d *Driver) redraw(clearScreen bool) {
 	if len(d.UIs) > 0 {
@@ -296,6 +434,10 @@ func (d *Driver) connectionStatus(connected bool) {
 	default: // don't block if there is no value
 	}
 
+	if !connected {
+		d.registers.Invalidate()
+	}
+
 	// Push the current connected status
 	d.monitorChan <- connected
 }
@@ -347,6 +489,7 @@ func (d *Driver) Draw(t *display.Terminal, connected, initialize bool) {
 	// Clock
 	t.PrintAtf(83, 4, "%sClock", common.Yellow)
 	t.PrintAt(85, 5, d.clock.Block())
+	t.PrintAt(83, 6, d.clock.ModeBlock())
 
 	// Reset
 	t.PrintAtf(93, 4, "%sReset", common.Yellow)
@@ -361,6 +504,9 @@ func (d *Driver) Draw(t *display.Terminal, connected, initialize bool) {
 	steps := d.opCode.Steps
 	t.PrintAt(55, 5, d.step.StepBlock(steps))
 
+	// Registers
+	t.PrintAt(55, 19, d.registers.RegistersBlock())
+
 	// Instructions
 	t.PrintAtf(58, 7, "%sInstructions", common.Yellow)
 	lines = d.memory.InstructionBlock(d.instrAddr, d.address)
@@ -384,16 +530,26 @@ func (d *Driver) Draw(t *display.Terminal, connected, initialize bool) {
 	var outputs[4]string
 	var AluOperations[4]string
 	flags := d.flags.DevFlags()
+	edit := d.editOpCode()
+	title := "Control Lines"
+	if d.editOp != nil {
+		title = "Control Lines: " + common.BrightMagenta + edit.Name + common.Yellow
+	}
 	if d.flags.Ignore {
-		t.PrintAtf(1, 20, "%sControl Lines (%s%s)%s", common.Yellow, d.flags.DevBlock(), common.Yellow, common.Reset)
+		t.PrintAtf(1, 20, "%s%s (%s%s)%s%s", common.Yellow, title, d.flags.DevBlock(), common.Yellow, common.Reset, display.ClearEnd)
 	} else {
 		flags = d.flags.CurrentFlags()
-		t.PrintAtf(1, 20, "%sControl Lines (Following flags)", common.Yellow)
+		t.PrintAtf(1, 20, "%s%s (Following flags)%s%s", common.Yellow, title, common.Reset, display.ClearEnd)
 	}
 	t.PrintAtf(66, 20, "%sActiveLines", common.Yellow)
+	if d.scope != scopeVariant {
+		t.PrintAtf(81, 20, "%sScope: %s%s%s%s", common.Yellow, common.BrightMagenta, d.scopeName(), common.Reset, display.ClearEnd)
+	} else {
+		t.PrintAtf(81, 20, "%s", display.ClearEnd)
+	}
 
-	lines, aLines, outputs, AluOperations = d.opCode.Block(flags, d.step.CurrentStep(), d.clock.CurrentState(), (d.lines.EditStep() - 1) / 2, (d.lines.EditStep() - 1) % 2)
-	d.lines.SetControlLines(d.opCode.Lines[flags])
+	lines, aLines, outputs, AluOperations = edit.Block(flags, d.step.CurrentStep(), d.clock.CurrentState(), (d.lines.EditStep() - 1) / 2, (d.lines.EditStep() - 1) % 2)
+	d.lines.SetControlLines(edit.Lines[flags])
 	for i := 0; i < 14; i++ {
 		str := ""
 		if i < len(lines) {
@@ -435,133 +591,202 @@ func (d *Driver) Draw(t *display.Terminal, connected, initialize bool) {
 	str := d.keyIntercept[d.editor].CursorPosition()
 	d.display.PrintAt(d.display.Cols()-9, 1, str)
 
-	// Notifications
-	max := d.display.Rows() - offset
+	// Notifications, above the command line while one is being typed
+	bottom := d.display.Rows()
+	if d.command {
+		d.display.PrintAt(1, bottom, d.commandLine())
+		bottom--
+	}
+	max := bottom - offset
 	lines = d.log.LogBlock(max)
 	for i := 0; i < max; i++ {
 		line := display.ClearLine
 		if i < len(lines) {
 			line = lines[i]
 		}
-		d.display.PrintAt(1, d.display.Rows()-i, line)
+		d.display.PrintAt(1, bottom-i, line)
 	}
 
 	// Restore cursor position
-	d.keyIntercept[d.editor].PositionCursor()
+	if d.command {
+		d.positionCommand()
+	} else {
+		d.keyIntercept[d.editor].PositionCursor()
+	}
 	d.display.ShowCursor()
 }
 func (d *Driver) Process(input common.Input) bool {
+	if d.command {
+		d.commandIntercept(input)
+		return false
+	}
 	if d.editor >= 0 && d.editor < len(d.keyIntercept) && d.keyIntercept[d.editor].KeyIntercept(input) {
 		return false
 	}
 
-	if input.KeyCode != 0 {
-		switch input.KeyCode {
-		default:
-			d.log.Warnf("Unknown code: [%v]", input.KeyCode)
+	action := d.keys.Action(keymap.Global, input)
+	switch action {
+	case "read_address":
+		if address, ok := d.serial.ReadAddress(); ok {
+			d.log.Infof("Read address: %s", display.HexAddress(address))
+		} else {
+			d.log.Warn("Failed to read address")
 		}
-	} else {
-		switch input.Ascii {
-		case 'a':
-			if address, ok := d.serial.ReadAddress(); ok {
-				d.log.Infof("Read address: %s", display.HexAddress(address))
-			} else {
-				d.log.Warn("Failed to read address")
-			}
-		case 'e':
-			if err := d.opCodes.Export(); err != nil {
-				d.log.Warnf("Export failed: %v", err)
+	case "export":
+		if err := d.opCodes.Export(); err != nil {
+			d.log.Warnf("Export failed: %v", err)
+		} else {
+			d.log.Info("Export complete")
+		}
+	case "quit":
+		return true
+	case "redo":
+		d.undo.Redo()
+	case "undo":
+		d.undo.Undo()
+	case "debug_off":
+		d.log.SetDebug(false)
+	case "toggle_breakpoint":
+		d.memory.ToggleBreakPoint(d.instrAddr)
+	case "debug_on":
+		d.log.SetDebug(true)
+	case "copy_lines", "copy_all_lines":
+		flags := d.flags.DevFlags()
+		if !d.flags.Ignore {
+			flags = d.flags.CurrentFlags()
+		}
+		mnemonics := d.editOpCode().DescribeLine(flags, (d.lines.EditStep() - 1) / 2, (d.lines.EditStep() - 1) % 2, 64, " | ", "CL_", action == "copy_lines")
+		if len(mnemonics) > 0 && strings.HasPrefix(mnemonics[0], "CL_CTMR") {
+			if strings.HasPrefix(mnemonics[0], "CL_CTMR | ") {
+				mnemonics = []string{mnemonics[0][10:]}
 			} else {
-				d.log.Info("Export complete")
-			}
-		case 'q':
-			return true
-		case 'D':
-			d.log.SetDebug(false)
-		case 'b':
-			d.memory.ToggleBreakPoint(d.instrAddr)
-		case 'd':
-			d.log.SetDebug(true)
-		case 'c', 'C':
-			flags := d.flags.DevFlags()
-			if !d.flags.Ignore {
-				flags = d.flags.CurrentFlags()
+				mnemonics = []string{mnemonics[0][7:]}
 			}
-			mnemonics := d.opCode.DescribeLine(flags, (d.lines.EditStep() - 1) / 2, (d.lines.EditStep() - 1) % 2, 64, " | ", "CL_", input.Ascii == 'c')
-			if len(mnemonics) > 0 && strings.HasPrefix(mnemonics[0], "CL_CTMR") {
-				if strings.HasPrefix(mnemonics[0], "CL_CTMR | ") {
-					mnemonics = []string{mnemonics[0][10:]}
+		}
+		if len(mnemonics) > 0 {
+			if err := clipboard.WriteAll(mnemonics[0]); err == nil {
+				if action == "copy_lines" {
+					d.log.Info("Mnemonics copied to clipboard without address mode lines")
 				} else {
-					mnemonics = []string{mnemonics[0][7:]}
+					d.log.Info("Mnemonics copied to clipboard")
 				}
+			} else {
+				d.log.Infof("Failed to copy lines to clipboard: %v", err)
 			}
-			if len(mnemonics) > 0 {
-				if err := clipboard.WriteAll(mnemonics[0]); err == nil {
-					if input.Ascii == 'c' {
-						d.log.Info("Mnemonics copied to clipboard without address mode lines")
-					} else {
-						d.log.Info("Mnemonics copied to clipboard")
-					}
+		} else {
+			if err := clipboard.WriteAll("0"); err != nil {
+				if action == "copy_lines" {
+					d.log.Info("No lines set outside of address mode lines")
 				} else {
-					d.log.Infof("Failed to copy lines to clipboard: %v", err)
+					d.log.Info("No lines set")
 				}
 			} else {
-				if err := clipboard.WriteAll("0"); err != nil {
-					if input.Ascii == 'c' {
-						d.log.Info("No lines set outside of address mode lines")
-					} else {
-						d.log.Info("No lines set")
-					}
-				} else {
-					d.log.Infof("Failed to copy lines to clipboard: %v", err)
-				}
+				d.log.Infof("Failed to copy lines to clipboard: %v", err)
 			}
+		}
 
-		case 'h':
-			d.UIs = append([]common.UI{d.helpPage.Help()}, d.UIs...)
-			d.redraw(true)
-		case 'l':
-			d.UIs = append([]common.UI{d.log.HistoryViewer()}, d.UIs...)
-			d.redraw(true)
-		case 'p':
-			d.UIs = append([]common.UI{d.serial.PortViewer()}, d.UIs...)
-			d.redraw(true)
-		case 'L':
+	case "help":
+		d.UIs = append([]common.UI{d.helpPage.Help()}, d.UIs...)
+		d.redraw(true)
+	case "log_history":
+		d.UIs = append([]common.UI{d.log.HistoryViewer(d.keys)}, d.UIs...)
+		d.redraw(true)
+	case "ports":
+		d.UIs = append([]common.UI{d.serial.PortViewer()}, d.UIs...)
+		d.redraw(true)
+	case "traffic":
+		d.UIs = append([]common.UI{d.serial.TrafficViewer()}, d.UIs...)
+		d.redraw(true)
+	case "breakpoints":
+		d.UIs = append([]common.UI{d.memory.BreakPointViewer()}, d.UIs...)
+		d.redraw(true)
+	case "step_phase":
+		d.StepPhase()
+	case "step_cycle":
+		d.StepCycle()
+	case "step_instruction":
+		d.StepInstruction()
+	case "run":
+		d.ToggleRun()
+	case "run_cycles":
+		d.RunCycles(uint32(config.CLIConfig.Clock.RunCycles))
+	case "profiler":
+		d.UIs = append([]common.UI{d.profiler}, d.UIs...)
+		d.redraw(true)
+	case "coverage":
+		d.UIs = append([]common.UI{d.coverage}, d.UIs...)
+		d.redraw(true)
+	case "waveform":
+		d.UIs = append([]common.UI{d.waveform}, d.UIs...)
+		d.redraw(true)
+	case "datapath":
+		d.UIs = append([]common.UI{d.datapath}, d.UIs...)
+		d.redraw(true)
+	case "query":
+		d.UIs = append([]common.UI{d.query}, d.UIs...)
+		d.redraw(true)
+	case "edit_scope":
+		d.CycleScope()
+	case "command":
+		d.OpenCommand()
+	case "mark_steps":
+		d.MarkSteps()
+	case "copy_steps":
+		d.CopySteps()
+	case "paste_steps":
+		d.PasteSteps()
+	case "step_over":
+		d.StepOver()
+	case "step_out":
+		d.StepOut()
+	case "run_to_cursor":
+		d.RunToCursor()
+	case "pulse_irq":
+		d.interrupts.Pulse(interrupts.IRQ)
+	case "pulse_nmi":
+		d.interrupts.Pulse(interrupts.NMI)
+	case "pulse_reset":
+		d.interrupts.Pulse(interrupts.RESET)
+	case "clock_faster":
+		d.SetClockHz(d.clockHz * 2)
+	case "clock_slower":
+		d.SetClockHz(d.clockHz / 2)
+	case "line_editor":
+		d.editor = 0
+		d.redraw(false)
+	case "memory_editor":
+		d.editor = 1
+		d.redraw(false)
+	case "bus_editor":
+		d.editor = 2
+		d.redraw(false)
+	case "flags_editor":
+		d.useDevFlags()
+		d.editor = 3
+		d.redraw(false)
+	case "toggle_flags":
+		d.flags.Ignore = !d.flags.Ignore
+		if d.flags.Ignore {
+			d.keyIntercept = append(d.keyIntercept, d.flags)
+			d.editor = len(d.keyIntercept) - 1
+		} else {
+			if d.editor == len(d.keyIntercept) - 1 { d.editor = 0 }
+			d.keyIntercept = d.keyIntercept[:len(d.keyIntercept) - 1]
+		}
+		d.redraw(true)
+	case "sync_flags":
+		d.flags.SyncFlags()
+	case "next_editor":
+		if d.editor + 1 >= len(d.keyIntercept) {
 			d.editor = 0
-			d.redraw(false)
-		case 'M':
-			d.editor = 1
-			d.redraw(false)
-		case 'B':
-			d.editor = 2
-			d.redraw(false)
-		case 'F':
-			if len(d.keyIntercept) == 3 {
-				d.flags.Ignore = true
-				d.keyIntercept = append(d.keyIntercept, d.flags)
-			}
-			d.editor = 3
-			d.redraw(false)
-		case 'f':
-			d.flags.Ignore = !d.flags.Ignore
-			if d.flags.Ignore {
-				d.keyIntercept = append(d.keyIntercept, d.flags)
-				d.editor = len(d.keyIntercept) - 1
-			} else {
-				if d.editor == len(d.keyIntercept) - 1 { d.editor = 0 }
-				d.keyIntercept = d.keyIntercept[:len(d.keyIntercept) - 1]
-			}
-			d.redraw(true)
-		case 's':
-			d.flags.SyncFlags()
-		case '\t':
-			if d.editor + 1 >= len(d.keyIntercept) {
-				d.editor = 0
-			} else {
-				d.editor += 1
-			}
-			d.redraw(false)
-		default:
+		} else {
+			d.editor += 1
+		}
+		d.redraw(false)
+	default:
+		if input.KeyCode != 0 {
+			d.log.Warnf("Unknown code: [%v]", input.KeyCode)
+		} else {
 			d.log.Warnf("Unmapped ascii code: [%c]", input.Ascii)
 		}
 	}
@@ -570,7 +795,11 @@ func (d *Driver) Process(input common.Input) bool {
 
 func (d *Driver) tickFunc(phaseChange bool) {
 
-	state, ok := d.serial.ReadStatus()
+	// Firmware with the tick command sends the status with the clock edge
+	state, ok := d.serial.EdgeStatus()
+	if !ok {
+		state, ok = d.serial.ReadStatus()
+	}
 	if ok {
 		d.step.SetStep(state)
 		d.flags.SetFlags(state)
@@ -600,48 +829,195 @@ func (d *Driver) tickFunc(phaseChange bool) {
 		flags = d.flags.CurrentFlags()
 	}
 	lines := d.opCode.Lines[flags][d.step.CurrentStep()][d.clock.CurrentState()]
-	d.serial.SetLines(lines, d.memory.HasBreakPoint(d.instrAddr))
+	d.coverage.Line(d.opCode.OpCode, flags, d.step.CurrentStep(), d.clock.CurrentState())
 
-	time.Sleep(50 * time.Millisecond)
-	if address, ok := d.serial.ReadAddress(); ok {
-		d.SetAddress(address)
+	// Firmware with the tick command reports the buses once the lines settle, saving the round-trips
+	var report *serial.TickReport
+	if d.serial.Supports('T') {
+		if r, ok := d.serial.Tick(lines, d.pauseRequested()); ok {
+			report = &r
+			d.step.SetStep(r.Status)
+			d.flags.SetFlags(r.Status)
+			d.SetAddress(r.Address)
+		} else {
+			d.log.Errorf("Failed to retrieve tick report")
+			d.ResetChannels()
+			return
+		}
 	} else {
-		d.log.Errorf("Failed to retrieve address")
-		d.ResetChannels()
-		return
+		d.serial.SetLines(lines, d.pauseRequested())
+
+		time.Sleep(50 * time.Millisecond)
+		if address, ok := d.serial.ReadAddress(); ok {
+			d.SetAddress(address)
+		} else {
+			d.log.Errorf("Failed to retrieve address")
+			d.ResetChannels()
+			return
+		}
 	}
 
+	sample := trace.Sample{
+		Cycle:   d.phaseCycle(),
+		OpCode:  d.opCode.OpCode,
+		Name:    d.opCode.Name,
+		Flags:   flags,
+		Step:    d.step.CurrentStep(),
+		Phase:   d.clock.CurrentState(),
+		Lines:   lines,
+		Address: d.address,
+	}
 	if d.address < 0x6000 || d.address >=0x6200 {
 		if d.clock.CurrentState() == instructionSet.PHI1 || lines&instructionSet.CL_DBRW != 0 {
 			if data, ok := d.memory.ReadMemory(d.address); ok {
-				d.serial.SetData(data)
+				// PHI1 only drives the bus ahead of the cycle; the read completes on PHI2
+				if d.clock.CurrentState() == instructionSet.PHI2 {
+					d.memory.WatchRead(d.address, data)
+					d.coverage.Read(d.address)
+				}
+				sample.Data, sample.HasData = data, true
+				d.sendData(data)
 			} else {
 				d.log.Errorf("Failed to read memory address %s during tick", display.HexAddress(d.address))
 				return
 			}
 		} else {
-			if data, ok := d.serial.ReadData(); ok {
+			if data, ok := d.readData(report); ok {
 				if ok = d.memory.WriteMemory(d.address, data); !ok {
 					d.log.Warnf("Failed to write %s @ %s", display.HexAddress(d.address), display.HexData(data))
 					return
 				}
+				d.coverage.Written(d.address)
+				sample.Data, sample.HasData = data, true
 			} else {
 				d.log.Errorf("Failed to read data during tick")
 				d.ResetChannels()
 				return
 			}
 		}
+		if hit, ok := d.memory.WatchTriggered(); ok {
+			d.log.Warn(hit)
+			if !d.pauseRequested() {
+				d.watchPause = true
+				d.serial.SetLines(lines, true)
+			}
+		}
+	}
+
+	d.recorder.Record(sample)
+
+	if d.clock.CurrentState() == instructionSet.PHI1 {
+		d.registers.Step()
+	}
+	if report != nil && report.Registers != nil {
+		d.registers.SetRegisters(report.Registers)
+	} else if report == nil {
+		if bs, ok := d.serial.ReadRegisters(); ok {
+			d.registers.SetRegisters(bs)
+		}
 	}
 
 	if d.clock.CurrentState() == 0 {
 		d.cycles++
+		d.profiler.Cycle()
+		d.interrupts.Cycle(d.cycles)
+	}
+	if d.editOp == nil {
+		d.lines.SetEditStep(d.step.CurrentStep() * 2 + d.clock.CurrentState() + 1)
 	}
-	d.lines.SetEditStep(d.step.CurrentStep() * 2 + d.clock.CurrentState() + 1)
+	d.continueStep()
 	d.log.Tracef("tickFunc. PhaseChange: %v. Clock: %v. Flags: %v. Phase %v", phaseChange, d.step.CurrentStep(), d.flags.CurrentFlags(), d.clock.CurrentState())
 	d.editor = 0
 	d.redraw(false)
 }
+// phaseCycle is the cycle the current phase belongs to. The count goes up as
+// PHI1 is handled, so PHI2 belongs to the cycle before.
+func (d *Driver) phaseCycle() uint64 {
+	if d.clock.CurrentState() == instructionSet.PHI2 && d.cycles > 0 {
+		return d.cycles - 1
+	}
+	return d.cycles
+}
+// currentOpCode gives the waveform view the opcode and flags being executed
+func (d *Driver) currentOpCode() (*instructionSet.OpCode, uint8) {
+	if d.flags.Ignore {
+		return d.opCode, d.flags.DevFlags()
+	}
+	return d.opCode, d.flags.CurrentFlags()
+}
+// jumpTo opens a query match in the control line editor, using developer flags to select its variant
+func (d *Driver) jumpTo(m *instructionSet.Match) {
+	d.editOpCodeOf(m.OpCode)
+	d.flags.SetDevFlags(m.Flags[0])
+	d.useDevFlags()
+	d.lines.SetEditStep(m.Step * 2 + m.Phase + 1)
+	d.log.Infof("Editing %s step %d Φ%d with flags %02d", m.OpCode.Name, m.Step, m.Phase + 1, m.Flags[0])
+}
+
+// editOpCode is the opcode shown in the control line editor: one opened by a query
+// or :opcode, otherwise the opcode being executed
+func (d *Driver) editOpCode() *instructionSet.OpCode {
+	if d.editOp != nil {
+		return d.editOp
+	}
+	return d.opCode
+}
+
+// editOpCodeOf opens an opcode in the editor without changing what drives the
+// board. Opening the executing opcode, or nil, follows execution again.
+func (d *Driver) editOpCodeOf(oc *instructionSet.OpCode) {
+	if oc == d.opCode {
+		oc = nil
+	}
+	d.editOp = oc
+	d.editor = 0
+}
+
+// useDevFlags selects microcode variants by the developer flags, adding the flags editor
+func (d *Driver) useDevFlags() {
+	if len(d.keyIntercept) == 3 {
+		d.flags.Ignore = true
+		d.keyIntercept = append(d.keyIntercept, d.flags)
+	}
+}
+
+// datapathState gives the datapath view the control word of the executing step, or of the edit step
+func (d *Driver) datapathState(edit bool) (*instructionSet.OpCode, uint8, uint8, uint64) {
+	if d.opCode == nil {
+		return nil, 0, 0, 0
+	}
+	oc, flags := d.currentOpCode()
+	step, phase := d.step.CurrentStep(), d.clock.CurrentState()
+	if edit {
+		oc = d.editOpCode()
+		step, phase = (d.lines.EditStep() - 1) / 2, (d.lines.EditStep() - 1) % 2
+	}
+	return oc, step, phase, oc.Lines[flags][step][phase]
+}
+func (d *Driver) romInfo() ([]uint16, int) {
+	return d.memory.Instructions(), d.memory.Size()
+}
+
+// sendData puts a byte on the data bus. The board holds the last byte sent, so
+// the PHI2 half of a read doesn't repeat the PHI1 exchange.
+func (d *Driver) sendData(data uint8) {
+	if d.sentData == int(data) {
+		return
+	}
+	if d.serial.SetData(data) {
+		d.sentData = int(data)
+	} else {
+		d.sentData = -1
+	}
+}
+func (d *Driver) readData(report *serial.TickReport) (uint8, bool) {
+	if report != nil {
+		return report.Data, report.Error == 0
+	}
+	return d.serial.ReadData()
+}
 func (d *Driver) SetOpCode(opCode uint8) {
+	d.watchPause = false
 	if d.opCode == nil || d.opCode.OpCode != opCode {
 		d.opCode = d.opCodes.Lookup(opCode)
 		if d.opCode.Virtual && (d.opCode.OpCode != 0x02 && d.opCode.OpCode != 0x12 && d.opCode.OpCode != 0x22) {
@@ -649,9 +1025,17 @@ func (d *Driver) SetOpCode(opCode uint8) {
 		}
 	}
 	d.instrAddr = d.address
+	d.profiler.Fetch(d.instrAddr, d.opCode.OpCode, d.opCode.Name)
+	d.coverage.Executed(d.instrAddr)
+	d.breakPause = d.memory.CheckBreakPoint(d.instrAddr, d)
+	if d.breakPause {
+		d.log.Infof("Paused at %s", display.HexAddress(d.instrAddr))
+		d.stopClock()
+	}
 	d.log.Debugf("Loaded OpCode: %s", d.opCode.Name)
 }
 func (d *Driver) ResetChannels() {
+	d.sentData = -1
 	for {
 		select {
 		case <-d.dispChan:
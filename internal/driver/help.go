@@ -1,16 +1,25 @@
 package driver
 
 import (
+	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"strings"
 )
 
+// HelpPage shows the bus source numbers, followed by the active key bindings,
+// which scroll when they don't fit
 type HelpPage struct {
+	keys   *keymap.Keymap
+	redraw func(bool)
+	list   common.List
 }
-func NewHelpPage() *HelpPage {
-	return &HelpPage{}
+func NewHelpPage(keys *keymap.Keymap, redraw func(bool)) *HelpPage {
+	return &HelpPage{keys: keys, redraw: redraw}
 }
 func (h *HelpPage) Help() common.UI {
+	h.list.Top()
 	return h
 }
 func (h *HelpPage) Draw(t *display.Terminal, connected bool, initialize bool) {
@@ -49,31 +58,51 @@ func (h *HelpPage) Draw(t *display.Terminal, connected bool, initialize bool) {
 	t.PrintAtf(61, 7, "%s5%s Data bus%s", common.Yellow, common.White, common.Reset)
 	t.PrintAtf(61, 8, "%s6%s Address high bus%s", common.Yellow, common.White, common.Reset)
 
-	t.PrintAtf( 1,10, "%sKey mappings%s", common.Yellow, common.Reset)
-	t.PrintAtf( 1,10, "%sd%s Debug enabled%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(21,10, "%sc%s Copies line lines%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(41,10, "%sf%s Toggle flag usage%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(61,10, "%sl%s Show log history%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(81,10, "%stab%s Toggle editors%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf( 1,11, "%sD%s Debug disabled%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(21,11, "%sC%s Copies all lines%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(41,11, "%sh%s Show this page%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(61,11, "%sp%s Show ports%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(81,11, "%sq%s   Quit%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf( 1,12, "%sL%s Ctrl Line editor%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(21,12, "%sM%s Memory editor%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(41,12, "%sB%s Bus editor%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(61,12, "%sF%s Flags editor%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(81,12, "%ss%s Sync dev flags%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf( 1,13, "%sb%s Toggle breakpoint%s", common.Yellow, common.White, common.Reset)
-
-	t.PrintAtf( 1,15, "%s0%s Deactivate line%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(21,15, "%s1%s Activate line%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(41,15, "%sspace%s Toggle line%s", common.Yellow, common.White, common.Reset)
-	t.PrintAtf(61,15, "%sdelete%s Reset line%s", common.Yellow, common.White, common.Reset)
-
-	t.PrintAtf(1, t.Rows(), "%sPress any key to exit%s", common.Yellow, common.Reset)
+	// Key bindings, five to a row under each scope's heading
+	lines := h.bindings()
+	h.list.Layout(t.Rows() - 10, len(lines))
+	for row := 0; row < h.list.Rows; row++ {
+		line := ""
+		if i, ok := h.list.Item(row); ok {
+			line = lines[i]
+		}
+		t.PrintAtf(1, row + 10, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, "up down pgup pgdn scroll, any other key to exit")
+}
+func (h *HelpPage) bindings() []string {
+	var lines []string
+	for _, scope := range keymap.Scopes {
+		lines = append(lines, fmt.Sprintf("%s%s%s", common.Yellow, keymap.Title(scope), common.Reset))
+		line := ""
+		for i, b := range h.keys.Bindings(scope) {
+			keys, description := b.KeyNames(), b.Description
+			if keys == "" {
+				keys = "-"
+			}
+			if width := 18 - len(keys); len(description) > width && width > 0 {
+				description = description[:width]
+			}
+			pad := 19 - len(keys) - len(description)
+			if pad < 1 {
+				pad = 1
+			}
+			line += fmt.Sprintf("%s%s%s %s%s%s", common.Yellow, keys, common.White, description, common.Reset, strings.Repeat(" ", pad))
+			if i % 5 == 4 {
+				lines = append(lines, line)
+				line = ""
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
 }
 func (h *HelpPage) Process(keyboard common.Input) bool {
+	if h.list.Scroll(keyboard) {
+		h.redraw(false)
+		return false
+	}
 	return true
 }
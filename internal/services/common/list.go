@@ -0,0 +1,103 @@
+package common
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+)
+
+// List is the scroll position of a page showing one item per row. A list with
+// Select set has a cursor, and scrolls to keep the selected item in view;
+// otherwise the arrow and page keys scroll the list directly.
+type List struct {
+	Select bool
+	Offset int
+	Cursor int
+	Rows   int
+	count  int
+}
+
+// Layout fits the list to the rows available for count items, clamping the offset and cursor
+func (l *List) Layout(rows int, count int) {
+	l.Rows, l.count = rows, count
+	l.clamp()
+}
+func (l *List) clamp() {
+	if l.Select {
+		if l.Cursor >= l.count {
+			l.Cursor = l.count - 1
+		}
+		if l.Cursor < 0 {
+			l.Cursor = 0
+		}
+		if l.Cursor < l.Offset {
+			l.Offset = l.Cursor
+		} else if l.Cursor >= l.Offset + l.Rows {
+			l.Offset = l.Cursor - l.Rows + 1
+		}
+	} else if l.Offset > l.count - l.Rows {
+		l.Offset = l.count - l.Rows
+	}
+	if l.Offset < 0 {
+		l.Offset = 0
+	}
+}
+
+// Scroll handles the arrow and page keys, returning false for any other input
+func (l *List) Scroll(input Input) bool {
+	switch input.KeyCode {
+	case display.CursorUp:
+		return l.Move("up")
+	case display.CursorDown:
+		return l.Move("down")
+	case display.PageUp:
+		return l.Move("page_up")
+	case display.PageDown:
+		return l.Move("page_down")
+	}
+	return false
+}
+
+// Move handles the up, down, page_up and page_down actions of a page's key
+// binding scope, returning false for any other action
+func (l *List) Move(action string) bool {
+	move := 0
+	switch action {
+	case "up":
+		move = -1
+	case "down":
+		move = 1
+	case "page_up":
+		move = -l.Rows
+	case "page_down":
+		move = l.Rows
+	default:
+		return false
+	}
+	if l.Select {
+		l.Cursor += move
+	} else {
+		l.Offset += move
+	}
+	l.clamp()
+	return true
+}
+
+// Item gives the index of the item shown on a row, and whether there is one
+func (l *List) Item(row int) (int, bool) {
+	i := l.Offset + row
+	return i, i < l.count
+}
+
+// AtEnd reports whether the last item is in view
+func (l *List) AtEnd() bool {
+	return l.Offset >= l.count - l.Rows
+}
+
+// Top scrolls back to the first item
+func (l *List) Top() {
+	l.Offset, l.Cursor = 0, 0
+}
+
+// Footer prints a page's key help on the bottom row
+func Footer(t *display.Terminal, help string) {
+	t.PrintAtf(1, t.Rows(), "%s%s%s%s", Yellow, help, Reset, display.ClearEnd)
+}
@@ -0,0 +1,267 @@
+package coverage
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Coverage records which microcode entries, Lines[flags][step][phase] of each
+// opcode, were executed, along with which ROM bytes were executed, read and written
+type Coverage struct {
+	lines    [256][16][8][2]bool
+	executed [65536]bool
+	read     [65536]bool
+	written  [65536]bool
+	opCodes  *instructionSet.OpCodes
+	rom      func() ([]uint16, int)
+	log      *logging.Log
+	redraw   func(bool)
+	keys     *keymap.Keymap
+	report   []string
+	list     common.List
+	sync     sync.Mutex
+}
+
+// New takes a function returning the ROM's instruction addresses and size
+func New(log *logging.Log, opCodes *instructionSet.OpCodes, rom func() ([]uint16, int), redraw func(bool), keys *keymap.Keymap) *Coverage {
+	return &Coverage{
+		log:     log,
+		opCodes: opCodes,
+		rom:     rom,
+		redraw:  redraw,
+		keys:    keys,
+	}
+}
+
+func (c *Coverage) Reset() {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	c.lines = [256][16][8][2]bool{}
+	c.executed = [65536]bool{}
+	c.read = [65536]bool{}
+	c.written = [65536]bool{}
+}
+
+// Line marks a control word as having been sent to the board
+func (c *Coverage) Line(opCode uint8, flags uint8, step uint8, phase uint8) {
+	if flags < 16 && step < 8 && phase < 2 {
+		c.sync.Lock()
+		c.lines[opCode][flags][step][phase] = true
+		c.sync.Unlock()
+	}
+}
+func (c *Coverage) Executed(address uint16) {
+	c.sync.Lock()
+	c.executed[address] = true
+	c.sync.Unlock()
+}
+
+// Read marks a byte as read by the CPU. Called once per read cycle, on PHI2.
+func (c *Coverage) Read(address uint16) {
+	c.sync.Lock()
+	c.read[address] = true
+	c.sync.Unlock()
+}
+func (c *Coverage) Written(address uint16) {
+	c.sync.Lock()
+	c.written[address] = true
+	c.sync.Unlock()
+}
+
+// variant is a class of flag values whose microcode is identical, so that
+// executing any one of them covers them all
+type variant struct {
+	members []uint8
+	steps   uint8
+	covered int
+}
+
+func (v *variant) entries() int {
+	return int(v.steps) * 2
+}
+
+// OpCodeReport summarises the coverage of one opcode
+type OpCodeReport struct {
+	OpCode    *instructionSet.OpCode
+	Covered   int
+	Total     int
+	Uncovered []string
+}
+
+func (r *OpCodeReport) Percent() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return float64(r.Covered) * 100 / float64(r.Total)
+}
+
+func (c *Coverage) opCodeReport(oc *instructionSet.OpCode) *OpCodeReport {
+	report := &OpCodeReport{OpCode: oc}
+	var classes []*variant
+	for _, members := range oc.Variants() {
+		classes = append(classes, &variant{members: members, steps: oc.StepCount(members[0])})
+	}
+	for _, v := range classes {
+		for step := uint8(0); step < v.steps; step++ {
+			for phase := 0; phase < 2; phase++ {
+				for _, m := range v.members {
+					if c.lines[oc.OpCode][m][step][phase] {
+						v.covered++
+						break
+					}
+				}
+			}
+		}
+		report.Covered += v.covered
+		report.Total += v.entries()
+		if len(classes) > 1 && v.covered == 0 {
+			report.Uncovered = append(report.Uncovered, oc.DescribeVariant(v.members) + " never executed")
+		} else if v.covered > 0 && v.covered < v.entries() {
+			report.Uncovered = append(report.Uncovered, fmt.Sprintf("%s %d of %d phases", oc.DescribeVariant(v.members), v.covered, v.entries()))
+		}
+	}
+	return report
+}
+
+// OpCodeReports covers every real opcode, plus the reset and interrupt pseudo-opcodes
+func (c *Coverage) OpCodeReports() []*OpCodeReport {
+	c.sync.Lock()
+	defer c.sync.Unlock()
+	var reports []*OpCodeReport
+	for op := 0; op < 256; op++ {
+		oc := c.opCodes.Lookup(uint8(op))
+		if oc == nil || oc.Virtual && op != 0x02 && op != 0x12 && op != 0x22 {
+			continue
+		}
+		reports = append(reports, c.opCodeReport(oc))
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		return reports[i].Percent() < reports[j].Percent()
+	})
+	return reports
+}
+
+// Report renders the coverage as text
+func (c *Coverage) Report() []string {
+	reports := c.OpCodeReports()
+	instructions, romSize := c.rom()
+	var lines []string
+
+	// Snapshot the byte maps, which the driver marks as it runs
+	c.sync.Lock()
+	executedAt, readAt, writtenAt := c.executed, c.read, c.written
+	c.sync.Unlock()
+
+	executed, read, written := 0, 0, 0
+	var missed []string
+	for _, address := range instructions {
+		if executedAt[address] {
+			executed++
+		} else if len(missed) < 16 {
+			missed = append(missed, "$" + display.HexAddress(address))
+		}
+	}
+	for address := 0; address < 65536; address++ {
+		if readAt[address] {
+			read++
+		}
+		if writtenAt[address] {
+			written++
+		}
+	}
+	lines = append(lines, "ROM")
+	lines = append(lines, fmt.Sprintf("  %d of %d instruction(s) executed (%.1f%%), %d byte(s) read, %d byte(s) written, %d byte ROM", executed, len(instructions), percent(executed, len(instructions)), read, written, romSize))
+	if len(missed) > 0 {
+		lines = append(lines, "  Not executed: " + strings.Join(missed, " "))
+	}
+
+	lines = append(lines, "", "Addressing modes")
+	modes := map[uint8][2]int{}
+	for _, r := range reports {
+		m := modes[r.OpCode.AddrMode]
+		modes[r.OpCode.AddrMode] = [2]int{m[0] + r.Covered, m[1] + r.Total}
+	}
+	for mode := 0; mode < len(instructionSet.AddressModeNames); mode++ {
+		if m, ok := modes[uint8(mode)]; ok {
+			name := instructionSet.AddressModeNames[mode]
+			if name == "" {
+				name = "---"
+			}
+			lines = append(lines, fmt.Sprintf("  %-4s %5d of %5d phases  %5.1f%%", name, m[0], m[1], percent(m[0], m[1])))
+		}
+	}
+
+	lines = append(lines, "", "Opcodes")
+	for _, r := range reports {
+		lines = append(lines, fmt.Sprintf("  $%s %-4s %-4s %3d of %3d phases  %5.1f%%", display.HexData(r.OpCode.OpCode), r.OpCode.Name, instructionSet.AddressModeNames[r.OpCode.AddrMode], r.Covered, r.Total, r.Percent()))
+		for _, u := range r.Uncovered {
+			lines = append(lines, "         " + u)
+		}
+	}
+	return lines
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(n) * 100 / float64(total)
+}
+
+func (c *Coverage) Export(filename string) error {
+	return ioutil.WriteFile(filename, []byte(strings.Join(c.Report(), "\n") + "\n"), 0644)
+}
+
+func (c *Coverage) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+		c.report = c.Report()
+	}
+	t.HideCursor()
+	c.list.Layout(t.Rows() - 3, len(c.report))
+	t.PrintAtf(1, 1, "%sMicrocode and ROM coverage%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	for row := 0; row < c.list.Rows; row++ {
+		line := ""
+		if i, ok := c.list.Item(row); ok {
+			line = c.report[i]
+			if strings.HasPrefix(line, "  ") {
+				line = common.White + line + common.Reset
+			} else {
+				line = common.Yellow + line + common.Reset
+			}
+		}
+		t.PrintAtf(1, row+2, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, c.keys.Help(keymap.Coverage, "export", "reset") + ", any other key to exit")
+}
+func (c *Coverage) Process(input common.Input) bool {
+	action := c.keys.Action(keymap.Coverage, input)
+	switch action {
+	case "up", "down", "page_up", "page_down":
+		c.list.Move(action)
+	case "reset":
+		c.Reset()
+		c.log.Info("Coverage reset")
+	case "export":
+		filename := strings.TrimSuffix(config.CLIConfig.RomFile, filepath.Ext(config.CLIConfig.RomFile)) + ".coverage.txt"
+		if err := c.Export(filename); err != nil {
+			c.log.Warnf("Coverage export failed: %v", err)
+		} else {
+			c.log.Infof("Coverage written to %s", filename)
+		}
+	default:
+		return true
+	}
+	c.redraw(true)
+	return false
+}
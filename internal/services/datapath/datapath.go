@@ -0,0 +1,248 @@
+package datapath
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"strings"
+)
+
+const (
+	busDB = iota
+	busSB
+	busABL
+	busABH
+	busCount
+)
+
+const (
+	labelWidth  = 16
+	columnWidth = 14
+	idle        = common.Grey
+	driving     = common.BrightGreen
+	latching    = common.BrightYellow
+)
+
+var busNames = [busCount]string{"DB", "SB", "ABL", "ABH"}
+
+// component is a register, or other block, attached to the buses. drives holds the
+// index of the bus driver selecting it, or -1 where it cannot drive that bus.
+type component struct {
+	name   string
+	drives [busCount]int
+	loads  func(d instructionSet.Datapath, phase uint8) [busCount]bool
+}
+
+func loadsFrom(bus int, line uint64) func(d instructionSet.Datapath, phase uint8) [busCount]bool {
+	return func(d instructionSet.Datapath, phase uint8) (loads [busCount]bool) {
+		loads[bus] = d.Asserted(line)
+		return
+	}
+}
+
+// Modelled on images/architecture.png, reduced to the registers of the breadboard
+var components = []component{
+	{"Input latch",   [busCount]int{ 6, -1,  0,  0}, nil},
+	{"PC high",       [busCount]int{ 4, -1, -1,  2}, loadsFrom(busABH, instructionSet.CL_PCLH)},
+	{"PC low",        [busCount]int{ 5, -1,  1, -1}, loadsFrom(busABL, instructionSet.CL_PCLL)},
+	{"PC low reg",    [busCount]int{-1, -1,  5, -1}, nil},
+	{"Constants",     [busCount]int{-1, -1,  2,  1}, nil},
+	{"Stack pointer", [busCount]int{-1,  4,  3, -1}, loadsFrom(busSB, instructionSet.CL_SPLD)},
+	{"Accumulator",   [busCount]int{ 1,  0, -1, -1}, loadsFrom(busSB, instructionSet.CL_SBLA)},
+	{"X register",    [busCount]int{-1,  2, -1, -1}, loadsFrom(busSB, instructionSet.CL_SBLX)},
+	{"Y register",    [busCount]int{-1,  1, -1, -1}, loadsFrom(busSB, instructionSet.CL_SBLY)},
+	{"ALU",           [busCount]int{-1,  3,  4, -1}, aluLoads},
+	{"Status",        [busCount]int{ 2, -1, -1, -1}, nil},
+	{"Addr bus low",  [busCount]int{-1, -1, -1, -1}, loadsFrom(busABL, instructionSet.CL_ALLD)},
+	{"Addr bus high", [busCount]int{-1, -1, -1, -1}, loadsFrom(busABH, instructionSet.CL_AHLD)},
+	{"Data out",      [busCount]int{-1, -1, -1, -1}, memoryWrite},
+}
+
+// aluLoads latches input A from the special bus, and input B from the data bus or address bus low
+func aluLoads(d instructionSet.Datapath, phase uint8) (loads [busCount]bool) {
+	loads[busSB] = d.Asserted(instructionSet.CL_AULA) && d.AluA.Index == 0
+	loads[busDB] = d.Asserted(instructionSet.CL_AULB) && d.AluB.Index == 0
+	loads[busABL] = d.Asserted(instructionSet.CL_AULB) && d.AluB.Index == 1
+	return
+}
+func memoryWrite(d instructionSet.Datapath, phase uint8) (loads [busCount]bool) {
+	loads[busDB] = phase == instructionSet.PHI2 && d.Asserted(instructionSet.CL_DBRW)
+	return
+}
+
+// bridges lists the buses that can drive each other, by bus and driver index
+var bridges = []struct {
+	bus    int
+	driver int
+	from   int
+}{{busDB, 3, busSB}, {busSB, 5, busDB}, {busSB, 6, busABH}, {busABH, 3, busSB}}
+
+// Datapath draws the registers and buses, highlighting which register drives
+// each bus and which latch from it for the current or edit step
+type Datapath struct {
+	state  func(edit bool) (*instructionSet.OpCode, uint8, uint8, uint64)
+	redraw func(bool)
+	keys   *keymap.Keymap
+	edit   bool
+}
+
+// New takes a function returning the opcode, step, phase and control word of the
+// executing step, or of the step being edited
+func New(state func(edit bool) (*instructionSet.OpCode, uint8, uint8, uint64), redraw func(bool), keys *keymap.Keymap) *Datapath {
+	return &Datapath{
+		state:  state,
+		redraw: redraw,
+		keys:   keys,
+	}
+}
+
+func busColumn(bus int) int {
+	return labelWidth + bus * columnWidth + columnWidth / 2
+}
+
+func (p *Datapath) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	t.HideCursor()
+	oc, step, phase, word := p.state(p.edit)
+	if oc == nil {
+		t.PrintAtf(1, 1, "%sDatapath: no opcode loaded%s%s", common.Yellow, common.Reset, display.ClearEnd)
+		return
+	}
+	source := "executing"
+	if p.edit {
+		source = "editing"
+	}
+	d := instructionSet.DecodeDatapath(word, phase)
+	t.PrintAtf(1, 1, "%sDatapath: %s %s step %d Φ%d (%s)%s%s", common.Yellow, display.HexData(oc.OpCode), oc.Name, step, phase + 1, source, common.Reset, display.ClearEnd)
+
+	drivers := [busCount]instructionSet.Ref{d.DB, d.SB, d.ABL, d.ABH}
+	busColour := [busCount]string{}
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", labelWidth))
+	for bus := 0; bus < busCount; bus++ {
+		busColour[bus] = idle
+		if !strings.HasPrefix(drivers[bus].Name, "None") {
+			busColour[bus] = common.BrightWhite
+		}
+		sb.WriteString(fmt.Sprintf("%s%*s%-*s", busColour[bus], columnWidth / 2 + len(busNames[bus]) / 2, busNames[bus], columnWidth - columnWidth / 2 - len(busNames[bus]) / 2, ""))
+	}
+	t.PrintAtf(1, 3, "%s%s%s", sb.String(), common.Reset, display.ClearEnd)
+	for bus := 0; bus < busCount; bus++ {
+		name := drivers[bus].Name
+		if len(name) > columnWidth - 1 {
+			name = name[:columnWidth - 1]
+		}
+		t.PrintAtf(labelWidth + bus * columnWidth + 1, 4, "%s%-*s%s", common.Cyan, columnWidth - 1, name, common.Reset)
+	}
+
+	row := 5
+	for _, c := range components {
+		t.PrintAtf(1, row, "%s%s", p.row(c, d, phase, drivers, busColour), display.ClearEnd)
+		row++
+	}
+
+	// Bridges between buses
+	var links []string
+	for _, b := range bridges {
+		if drivers[b.bus].Index == b.driver {
+			links = append(links, fmt.Sprintf("%s → %s", busNames[b.from], busNames[b.bus]))
+		}
+	}
+	row++
+	t.PrintAtf(1, row, "%sBridges: %s%s%s%s", common.Yellow, common.White, strings.Join(links, ", "), common.Reset, display.ClearEnd)
+	row++
+	carry := ""
+	if d.Asserted(instructionSet.CL_CENB) {
+		carry = ", carry in"
+	}
+	invert := ""
+	if d.Asserted(instructionSet.CL_AUIB) {
+		invert = " inverted"
+	}
+	t.PrintAtf(1, row, "%sALU: %sA %s, B %s%s, %s %s%s%s%s", common.Yellow, common.White, d.AluA.Name, d.AluB.Name, invert, d.AluOp.Name, d.AluDir.Name, carry, common.Reset, display.ClearEnd)
+	row++
+	var misc []string
+	if d.Asserted(instructionSet.CL_PCIN) {
+		misc = append(misc, "PC increment")
+	}
+	if phase == instructionSet.PHI2 && d.Asserted(instructionSet.CL_DBRW) {
+		misc = append(misc, "memory write")
+	} else {
+		misc = append(misc, "memory read")
+	}
+	if d.Asserted(instructionSet.CL_CTMR) {
+		misc = append(misc, "end of instruction")
+	}
+	t.PrintAtf(1, row, "%sOther: %s%s%s%s", common.Yellow, common.White, strings.Join(misc, ", "), common.Reset, display.ClearEnd)
+
+	t.PrintAtf(1, t.Rows(), "%s%s●%s drives bus  %s◆%s latches from bus  %s○%s idle connection, %s, any other key to exit%s%s", common.Yellow, driving, common.Yellow, latching, common.Yellow, idle, common.Yellow, p.keys.Help(keymap.Datapath, "edit"), common.Reset, display.ClearEnd)
+}
+
+// row draws a component and its connections to each bus
+func (p *Datapath) row(c component, d instructionSet.Datapath, phase uint8, drivers [busCount]instructionSet.Ref, busColour [busCount]string) string {
+	var loads [busCount]bool
+	if c.loads != nil {
+		loads = c.loads(d, phase)
+	}
+	last := -1
+	nameColour := common.White
+	for bus := 0; bus < busCount; bus++ {
+		if c.drives[bus] >= 0 || c.loads != nil && p.canLoad(c, bus) {
+			last = bus
+		}
+		if c.drives[bus] >= 0 && drivers[bus].Index == c.drives[bus] {
+			nameColour = driving
+		} else if loads[bus] && nameColour != driving {
+			nameColour = latching
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s%-*s", nameColour, labelWidth, c.name))
+	for bus := 0; bus < busCount; bus++ {
+		left := strings.Repeat(" ", columnWidth / 2)
+		right := strings.Repeat(" ", columnWidth - columnWidth / 2 - 1)
+		if bus <= last {
+			left = idle + strings.Repeat("─", columnWidth / 2)
+		}
+		if bus < last {
+			right = idle + strings.Repeat("─", columnWidth - columnWidth / 2 - 1)
+		}
+		centre := busColour[bus] + "│"
+		switch {
+		case c.drives[bus] >= 0 && drivers[bus].Index == c.drives[bus]:
+			centre = driving + "●"
+		case loads[bus]:
+			centre = latching + "◆"
+		case c.drives[bus] >= 0 || c.loads != nil && p.canLoad(c, bus):
+			centre = idle + "○"
+		}
+		sb.WriteString(left + centre + right)
+	}
+	return sb.String() + common.Reset
+}
+
+// canLoad reports whether a component has a load line from a bus, whatever the current word
+func (p *Datapath) canLoad(c component, bus int) bool {
+	all := instructionSet.Datapath{Active: ^uint64(0)}
+	for _, aluB := range []int{0, 1} {
+		all.AluB.Index = aluB
+		if loads := c.loads(all, instructionSet.PHI2); loads[bus] {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Datapath) Process(input common.Input) bool {
+	if p.keys.Action(keymap.Datapath, input) == "edit" {
+		p.edit = !p.edit
+		p.redraw(true)
+		return false
+	}
+	return true
+}
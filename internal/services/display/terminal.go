@@ -31,6 +31,8 @@ const (
 	CursorDown  = 40
 	CursorLeft  = 37
 	CursorRight = 39
+	PageUp      = 33
+	PageDown    = 34
 
 	// Show / Hide cursor
 	Show = "\u001b[?25h"
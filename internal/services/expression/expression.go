@@ -0,0 +1,429 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expressions are small integer formulas typed into the editors, for example
+// "$0200+$10", "cursor & $FF00" or "A == $10 && C". Numbers may be written as
+// $hex, 0xhex, %binary or decimal. Identifiers are resolved through an Env
+// supplied by the caller at evaluation time, and "[addr]" reads a byte of
+// memory when the Env also implements MemoryReader. Comparisons and logical
+// operators yield 1 or 0, with operator precedence following Go. The words
+// "and", "or" and "not" may be used in place of &&, || and !.
+//
+// Address prompts use the Hex variants, where an unprefixed number such as 0200
+// or C000 is hex and decimal needs a # prefix, e.g. #512.
+
+type Env interface {
+	Lookup(name string) (int64, bool)
+}
+type MemoryReader interface {
+	Peek(address uint16) uint8
+}
+
+type Node interface {
+	Eval(env Env) (int64, error)
+}
+
+type number struct {
+	value int64
+}
+type identifier struct {
+	name string
+}
+type unary struct {
+	op      string
+	operand Node
+}
+type binary struct {
+	op          string
+	left, right Node
+}
+type deref struct {
+	address Node
+}
+
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+":  4, "-": 4, "|": 4, "^": 4,
+	"*":  5, "/": 5, "%": 5, "<<": 5, ">>": 5, "&": 5,
+}
+
+// Parse compiles an expression so it can be evaluated repeatedly
+func Parse(text string) (Node, error) {
+	return parse(text, false)
+}
+
+// ParseHex compiles an expression whose unprefixed numbers are hex
+func ParseHex(text string) (Node, error) {
+	return parse(text, true)
+}
+func parse(text string, hex bool) (Node, error) {
+	p := &parser{hex: hex}
+	if err := p.tokenize(text); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	node, err := p.expression(1)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+// Eval parses and evaluates an expression in a single call
+func Eval(text string, env Env) (int64, error) {
+	node, err := Parse(text)
+	if err != nil {
+		return 0, err
+	}
+	return node.Eval(env)
+}
+
+// EvalHex is Eval with unprefixed numbers read as hex
+func EvalHex(text string, env Env) (int64, error) {
+	node, err := ParseHex(text)
+	if err != nil {
+		return 0, err
+	}
+	return node.Eval(env)
+}
+
+// IsTrue evaluates a compiled expression as a condition
+func IsTrue(node Node, env Env) (bool, error) {
+	value, err := node.Eval(env)
+	return value != 0, err
+}
+
+// Identifiers lists the names used in a compiled expression, so that they can be
+// checked before it is first evaluated
+func Identifiers(node Node) []string {
+	switch n := node.(type) {
+	case *identifier:
+		return []string{n.name}
+	case *unary:
+		return Identifiers(n.operand)
+	case *deref:
+		return Identifiers(n.address)
+	case *binary:
+		return append(Identifiers(n.left), Identifiers(n.right)...)
+	}
+	return nil
+}
+
+// EvalAddress evaluates an expression and checks it falls within the 64K address space
+func EvalAddress(text string, env Env) (uint16, error) {
+	value, err := Eval(text, env)
+	return address(value, err)
+}
+
+// EvalAddressHex is EvalAddress with unprefixed numbers read as hex, for address prompts
+func EvalAddressHex(text string, env Env) (uint16, error) {
+	value, err := EvalHex(text, env)
+	return address(value, err)
+}
+func address(value int64, err error) (uint16, error) {
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > 0xFFFF {
+		return 0, fmt.Errorf("address out of range: %d", value)
+	}
+	return uint16(value), nil
+}
+
+const (
+	tokNumber = iota
+	tokIdent
+	tokOperator
+)
+
+type token struct {
+	kind  int
+	text  string
+	value int64
+}
+type parser struct {
+	tokens []token
+	pos    int
+	hex    bool
+}
+
+func (p *parser) tokenize(text string) error {
+	i := 0
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '$' || c == '#' || c == '%' && p.expectsOperand() || c >= '0' && c <= '9':
+			j, base := i, 10
+			if p.hex {
+				base = 16
+			}
+			if c == '$' {
+				j, base = i+1, 16
+			} else if c == '#' {
+				j, base = i+1, 10
+			} else if c == '%' {
+				j, base = i+1, 2
+			} else if c == '0' && i+1 < len(text) && (text[i+1] == 'x' || text[i+1] == 'X') {
+				j, base = i+2, 16
+			}
+			k := j
+			for k < len(text) && isDigit(text[k], base) {
+				k++
+			}
+			if k == j {
+				return fmt.Errorf("malformed number at '%s'", text[i:])
+			}
+			value, err := strconv.ParseInt(text[j:k], base, 64)
+			if err != nil {
+				return fmt.Errorf("invalid number '%s': %v", text[i:k], err)
+			}
+			p.tokens = append(p.tokens, token{kind: tokNumber, text: text[i:k], value: value})
+			i = k
+		case isLetter(c):
+			j := i
+			for j < len(text) && (isLetter(text[j]) || text[j] >= '0' && text[j] <= '9') {
+				j++
+			}
+			if op, ok := keywords[strings.ToLower(text[i:j])]; ok {
+				p.tokens = append(p.tokens, token{kind: tokOperator, text: op})
+			} else if value, err := strconv.ParseInt(text[i:j], 16, 64); p.hex && err == nil {
+				// A word of hex digits, such as C000, is a number
+				p.tokens = append(p.tokens, token{kind: tokNumber, text: text[i:j], value: value})
+			} else {
+				p.tokens = append(p.tokens, token{kind: tokIdent, text: text[i:j]})
+			}
+			i = j
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(text[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return fmt.Errorf("unexpected character '%c'", c)
+			}
+			p.tokens = append(p.tokens, token{kind: tokOperator, text: op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// A '%' is a binary prefix unless it follows something that can be the left-hand side of a modulo
+func (p *parser) expectsOperand() bool {
+	if len(p.tokens) == 0 {
+		return true
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return last.kind == tokOperator && last.text != ")" && last.text != "]"
+}
+
+// Longest operators first so "<<" wins over "<"
+var operators = []string{
+	"<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"<", ">", "+", "-", "*", "/", "%", "&", "|", "^", "~", "!", "(", ")", "[", "]",
+}
+
+var keywords = map[string]string{"and": "&&", "or": "||", "not": "!"}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return token{}, false
+}
+func (p *parser) expression(minPrecedence int) (Node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOperator {
+			return left, nil
+		}
+		prec, isBinary := precedence[t.text]
+		if !isBinary || prec < minPrecedence {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.expression(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: t.text, left: left, right: right}
+	}
+}
+func (p *parser) operand() (Node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokNumber:
+		return &number{value: t.value}, nil
+	case tokIdent:
+		return &identifier{name: t.text}, nil
+	}
+	switch t.text {
+	case "-", "~", "+", "!":
+		operand, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: t.text, operand: operand}, nil
+	case "(":
+		node, err := p.expression(1)
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.text != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return node, nil
+	case "[":
+		node, err := p.expression(1)
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.text != "]" {
+			return nil, fmt.Errorf("missing ']'")
+		}
+		p.pos++
+		return &deref{address: node}, nil
+	}
+	return nil, fmt.Errorf("unexpected '%s'", t.text)
+}
+
+func (n *number) Eval(env Env) (int64, error) {
+	return n.value, nil
+}
+func (n *identifier) Eval(env Env) (int64, error) {
+	if env != nil {
+		if value, ok := env.Lookup(n.name); ok {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown identifier '%s'", n.name)
+}
+func (n *unary) Eval(env Env) (int64, error) {
+	value, err := n.operand.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "-":
+		return -value, nil
+	case "~":
+		return ^value, nil
+	case "!":
+		return boolean(value == 0), nil
+	}
+	return value, nil
+}
+func (n *deref) Eval(env Env) (int64, error) {
+	address, err := n.address.Eval(env)
+	if err != nil {
+		return 0, err
+	} else if address < 0 || address > 0xFFFF {
+		return 0, fmt.Errorf("address out of range: %d", address)
+	}
+	reader, ok := env.(MemoryReader)
+	if !ok {
+		return 0, fmt.Errorf("memory is not available here")
+	}
+	return int64(reader.Peek(uint16(address))), nil
+}
+func (n *binary) Eval(env Env) (int64, error) {
+	l, err := n.left.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	// Short circuit logical operators so "hits > 3 && [$0200] == 0" only reads when needed
+	if n.op == "&&" && l == 0 || n.op == "||" && l != 0 {
+		return boolean(l != 0), nil
+	}
+	r, err := n.right.Eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if n.op == "/" {
+			return l / r, nil
+		}
+		return l % r, nil
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "<<":
+		return l << uint64(r), nil
+	case ">>":
+		return l >> uint64(r), nil
+	case "&&", "||":
+		return boolean(r != 0), nil
+	case "==":
+		return boolean(l == r), nil
+	case "!=":
+		return boolean(l != r), nil
+	case "<":
+		return boolean(l < r), nil
+	case "<=":
+		return boolean(l <= r), nil
+	case ">":
+		return boolean(l > r), nil
+	case ">=":
+		return boolean(l >= r), nil
+	}
+	return 0, fmt.Errorf("unsupported operator '%s'", n.op)
+}
+
+func boolean(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 16:
+		return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+	}
+	return c >= '0' && c <= '9'
+}
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
@@ -0,0 +1,184 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+)
+
+// testEnv resolves identifiers from a map and memory from a sparse byte map,
+// counting reads so tests can check short circuiting
+type testEnv struct {
+	names  map[string]int64
+	memory map[uint16]uint8
+	reads  int
+}
+
+func (e *testEnv) Lookup(name string) (int64, bool) {
+	value, ok := e.names[strings.ToLower(name)]
+	return value, ok
+}
+func (e *testEnv) Peek(address uint16) uint8 {
+	e.reads++
+	return e.memory[address]
+}
+
+func newEnv() *testEnv {
+	return &testEnv{
+		names:  map[string]int64{"a": 0x10, "x": 3, "c": 1, "z": 0, "hits": 4},
+		memory: map[uint16]uint8{0x0200: 0x42, 0x0201: 0x02},
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"42", 42},
+		{"$ff", 0xFF},
+		{"0x10", 16},
+		{"%101", 5},
+		{"10 % 4", 2},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"1 << 2 + 1", 5},
+		{"$F0 | $0F & $03", 0xF3},
+		{"8 - 2 - 1", 5},
+		{"-x + 5", 2},
+		{"~0 & $FF", 0xFF},
+		{"1 + 1 == 2", 1},
+		{"a == $10 && c", 1},
+		{"a == $10 && z", 0},
+		{"z || x > 2", 1},
+		{"0 || 1 && 0", 0},
+		{"!z", 1},
+		{"[$0200]", 0x42},
+		{"[$0200 + 1] + 1", 3},
+		{"[[$0201] * $100]", 0x42},
+		{"a == $10 and not z", 1},
+		{"z or c", 1},
+		{"NOT c", 0},
+		{"hits > 3 and [$0200] == $42", 1},
+	}
+	for _, test := range tests {
+		got, err := Eval(test.text, newEnv())
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", test.text, err)
+		} else if got != test.want {
+			t.Errorf("Eval(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  int64
+		reads int
+	}{
+		{"z && [$0200]", 0, 0},
+		{"c || [$0200]", 1, 0},
+		{"c && [$0200]", 1, 1},
+		{"z || [$0200] == $42", 1, 1},
+		{"z and unknown", 0, 0},
+		{"c or unknown", 1, 0},
+	}
+	for _, test := range tests {
+		env := newEnv()
+		got, err := Eval(test.text, env)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", test.text, err)
+			continue
+		}
+		if got != test.want || env.reads != test.reads {
+			t.Errorf("Eval(%q) = %d with %d read(s), want %d with %d", test.text, got, env.reads, test.want, test.reads)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"[$0200",
+		"1 2",
+		"$",
+		"1 / z",
+		"5 % 0",
+		"unknown",
+		"[$10000]",
+		"1 @ 2",
+	}
+	for _, text := range tests {
+		if got, err := Eval(text, newEnv()); err == nil {
+			t.Errorf("Eval(%q) = %d, want an error", text, got)
+		}
+	}
+}
+
+func TestEvalHex(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"0200", 0x0200},
+		{"C000", 0xC000},
+		{"c000 + 10", 0xC010},
+		{"#512", 512},
+		{"$10 + #10", 0x1A},
+		{"%11", 3},
+		{"0x20", 0x20},
+		{"ff & f0", 0xF0},
+		{"x + 1", 4},
+		{"a", 0xA},
+		{"c", 0xC},
+	}
+	for _, test := range tests {
+		got, err := EvalHex(test.text, newEnv())
+		if err != nil {
+			t.Errorf("EvalHex(%q) failed: %v", test.text, err)
+		} else if got != test.want {
+			t.Errorf("EvalHex(%q) = %X, want %X", test.text, got, test.want)
+		}
+	}
+}
+
+func TestEvalAddress(t *testing.T) {
+	tests := []struct {
+		text string
+		hex  bool
+		want uint16
+		ok   bool
+	}{
+		{"$FFFF", false, 0xFFFF, true},
+		{"65536", false, 0, false},
+		{"-1", false, 0, false},
+		{"FFFC", true, 0xFFFC, true},
+		{"10000", true, 0, false},
+		{"#1024", true, 0x0400, true},
+	}
+	for _, test := range tests {
+		var got uint16
+		var err error
+		if test.hex {
+			got, err = EvalAddressHex(test.text, newEnv())
+		} else {
+			got, err = EvalAddress(test.text, newEnv())
+		}
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("address %q (hex %v) = %04X, %v, want %04X ok %v", test.text, test.hex, got, err, test.want, test.ok)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	node, err := Parse("a == $10 && [pc + x] != 0 or not hits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(Identifiers(node), " ")
+	if want := "a pc x hits"; got != want {
+		t.Errorf("Identifiers = %q, want %q", got, want)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"strings"
 )
 
 const (
@@ -231,6 +233,7 @@ type BusController struct {
 	step      uint8
 	clock     uint8
 	setLines  func(step uint8, clock uint8, bit uint64, value uint8)
+	keys      *keymap.Keymap
 }
 type ControlLines struct {
 	xOffset   []int
@@ -242,8 +245,9 @@ type ControlLines struct {
 	redraw    func(bool)
 	setLine   func(step uint8, clock uint8, bit uint64, value uint8)
 	busCntrl  *BusController
+	keys      *keymap.Keymap
 }
-func NewControlLines(log *logging.Log, terminal *display.Terminal, redraw func(bool),
+func NewControlLines(log *logging.Log, terminal *display.Terminal, redraw func(bool), keys *keymap.Keymap,
 	setLine func(step uint8, clock uint8, bit uint64, value uint8)) *ControlLines {
 	l := ControlLines{
 		log:      log,
@@ -253,6 +257,7 @@ func NewControlLines(log *logging.Log, terminal *display.Terminal, redraw func(b
 		yOffset:  20,
 		setLine:  setLine,
 		redraw:   redraw,
+		keys:     keys,
 		busCntrl: &BusController{
 			terminal: terminal,
 			redraw:   redraw,
@@ -260,6 +265,7 @@ func NewControlLines(log *logging.Log, terminal *display.Terminal, redraw func(b
 			yOffset:  []int{8, 10},
 			cursor:   common.Coord{X: 0, Y: 0},
 			setLines: setLine,
+			keys:     keys,
 		},
 	}
 	return &l
@@ -325,39 +331,31 @@ func (l *ControlLines) SetControlLines(ctrlLines [8][2]uint64) {
 }
 
 func (l *ControlLines) KeyIntercept(input common.Input) bool {
-	if input.KeyCode != 0 {
-		switch input.KeyCode {
-		case display.CursorUp:
-			l.Up(1)
-		case display.CursorDown:
-			l.Down(1)
-		case display.CursorLeft:
-			l.Left(1)
-		case display.CursorRight:
-			l.Right(1)
-		default:
-			// keycode not processed
-			return false
-		}
-	} else {
-		value := uint8(3)
-		switch input.Ascii {
-		case '1', '0', 0x7F, ' ':
-			if input.Ascii == '0' {
-				value = 0
-			} else if input.Ascii == '1' {
-				value = 1
-			} else if input.Ascii == 0x7F {
-				value = 2
-			}
-			step  := uint8((l.cursor.Y - 1) / 2)
-			clock := uint8((l.cursor.Y - 1) % 2)
-			bit   := uint64(47 - (l.cursor.X - 1) % 64)
-			l.setLine(step, clock, bit, value)
-		default:
-			// key not processed
-			return false
+	value := uint8(3)
+	switch action := l.keys.Action(keymap.Lines, input); action {
+	case "up":
+		l.Up(1)
+	case "down":
+		l.Down(1)
+	case "left":
+		l.Left(1)
+	case "right":
+		l.Right(1)
+	case "deactivate", "activate", "reset", "toggle":
+		if action == "deactivate" {
+			value = 0
+		} else if action == "activate" {
+			value = 1
+		} else if action == "reset" {
+			value = 2
 		}
+		step  := uint8((l.cursor.Y - 1) / 2)
+		clock := uint8((l.cursor.Y - 1) % 2)
+		bit   := uint64(47 - (l.cursor.X - 1) % 64)
+		l.setLine(step, clock, bit, value)
+	default:
+		// key not processed
+		return false
 	}
 	// key processed
 	return true
@@ -421,21 +419,17 @@ func (b *BusController) findNext(offset int) (uint64, bool)  {
 	return 0, false
 }
 func (b *BusController) KeyIntercept(input common.Input) bool {
-	if input.KeyCode != 0 {
-		switch input.KeyCode {
-		case display.CursorUp:
-			b.Up(1)
-		case display.CursorDown:
-			b.Down(1)
-		case display.CursorLeft:
-			b.Left(1)
-		case display.CursorRight:
-			b.Right(1)
-		default:
-			// keycode not processed
-			return false
-		}
-	} else {
+	switch b.keys.Action(keymap.Bus, input) {
+	case "up":
+		b.Up(1)
+	case "down":
+		b.Down(1)
+	case "left":
+		b.Left(1)
+	case "right":
+		b.Right(1)
+	default:
+		// key not processed
 		return false
 	}
 	// key processed
@@ -447,3 +441,55 @@ func (b *BusController) PositionCursor() {
 func (b *BusController) CursorPosition() string {
 	return fmt.Sprintf("      %s", busNames[b.cursor.Y])
 }
+
+// Mnemonics lists the control lines in EPROM order, most significant bit first
+func Mnemonics() []string {
+	return append([]string(nil), mnemonics...)
+}
+
+// LineBit finds the bit of a control line by its mnemonic, with or without the CL_ prefix
+func LineBit(name string) (uint64, bool) {
+	name = strings.TrimPrefix(strings.ToUpper(name), "CL_")
+	for index, mnemonic := range mnemonics {
+		if mnemonic == name {
+			return uint64(1) << (47 - index), true
+		}
+	}
+	return 0, false
+}
+
+// ActiveLow reports whether a control line is asserted by driving it low
+func ActiveLow(bit uint64) bool {
+	return Defaults[PHI1] & bit != 0
+}
+
+// Datapath is a control word decoded into what drives each bus, how the ALU is
+// set up, and which of the load lines are active
+type Datapath struct {
+	DB     Ref
+	ABL    Ref
+	ABH    Ref
+	SB     Ref
+	AluA   Ref
+	AluB   Ref
+	AluOp  Ref
+	AluDir Ref
+	Active uint64
+}
+
+func DecodeDatapath(word uint64, clock uint8) Datapath {
+	return Datapath{
+		DB:     OutputsDB [word & busLines[0]],
+		ABH:    OutputsABH[word & busLines[1]],
+		ABL:    OutputsABL[word & busLines[2]],
+		SB:     OutputsSB [word & busLines[3]],
+		AluB:   AluB  [word & busLines[4]],
+		AluA:   AluA  [word & busLines[5]],
+		AluOp:  AluOp [word & busLines[6]],
+		AluDir: AluDir[word & busLines[7]],
+		Active: word ^ Defaults[clock],
+	}
+}
+func (d Datapath) Asserted(bit uint64) bool {
+	return d.Active & bit != 0
+}
@@ -0,0 +1,236 @@
+package instructionSet
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// docWriter renders the reference documentation in one output format
+type docWriter interface {
+	heading(level int, anchor string, text string)
+	paragraph(text string)
+	table(header []string, rows [][]string)
+	text(s string) string
+	code(s string) string
+	link(text string, anchor string) string
+	String() string
+}
+
+type markdownWriter struct {
+	sb strings.Builder
+}
+
+func (w *markdownWriter) heading(level int, anchor string, text string) {
+	if anchor != "" {
+		fmt.Fprintf(&w.sb, "<a id=\"%s\"></a>\n\n", anchor)
+	}
+	fmt.Fprintf(&w.sb, "%s %s\n\n", strings.Repeat("#", level), text)
+}
+func (w *markdownWriter) paragraph(text string) {
+	fmt.Fprintf(&w.sb, "%s\n\n", text)
+}
+func (w *markdownWriter) table(header []string, rows [][]string) {
+	fmt.Fprintf(&w.sb, "| %s |\n|%s\n", strings.Join(header, " | "), strings.Repeat(" --- |", len(header)))
+	for _, row := range rows {
+		fmt.Fprintf(&w.sb, "| %s |\n", strings.Join(row, " | "))
+	}
+	w.sb.WriteString("\n")
+}
+func (w *markdownWriter) text(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+func (w *markdownWriter) code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+func (w *markdownWriter) link(text string, anchor string) string {
+	return fmt.Sprintf("[%s](#%s)", text, anchor)
+}
+func (w *markdownWriter) String() string {
+	return w.sb.String()
+}
+
+type htmlWriter struct {
+	sb strings.Builder
+}
+
+func (w *htmlWriter) heading(level int, anchor string, text string) {
+	id := ""
+	if anchor != "" {
+		id = fmt.Sprintf(" id=\"%s\"", anchor)
+	}
+	fmt.Fprintf(&w.sb, "<h%d%s>%s</h%d>\n", level, id, text, level)
+}
+func (w *htmlWriter) paragraph(text string) {
+	fmt.Fprintf(&w.sb, "<p>%s</p>\n", text)
+}
+func (w *htmlWriter) table(header []string, rows [][]string) {
+	w.sb.WriteString("<table>\n<tr>")
+	for _, h := range header {
+		fmt.Fprintf(&w.sb, "<th>%s</th>", h)
+	}
+	w.sb.WriteString("</tr>\n")
+	for _, row := range rows {
+		w.sb.WriteString("<tr>")
+		for _, cell := range row {
+			fmt.Fprintf(&w.sb, "<td>%s</td>", cell)
+		}
+		w.sb.WriteString("</tr>\n")
+	}
+	w.sb.WriteString("</table>\n")
+}
+func (w *htmlWriter) text(s string) string {
+	return html.EscapeString(s)
+}
+func (w *htmlWriter) code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<code>" + html.EscapeString(s) + "</code>"
+}
+func (w *htmlWriter) link(text string, anchor string) string {
+	return fmt.Sprintf("<a href=\"#%s\">%s</a>", anchor, html.EscapeString(text))
+}
+func (w *htmlWriter) String() string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Microcode reference</title>\n" +
+		"<style>body{font-family:sans-serif} table{border-collapse:collapse;margin-bottom:1em} " +
+		"th,td{border:1px solid #ccc;padding:2px 6px;text-align:left} code{font-size:90%}</style>\n" +
+		"</head>\n<body>\n" + w.sb.String() + "</body>\n</html>\n"
+}
+
+// Markdown documents the whole instruction set as Markdown
+func (op *OpCodes) Markdown() string {
+	w := &markdownWriter{}
+	op.document(w)
+	return w.String()
+}
+
+// HTML documents the whole instruction set as a standalone HTML page
+func (op *OpCodes) HTML() string {
+	w := &htmlWriter{}
+	op.document(w)
+	return w.String()
+}
+
+// Documented lists the real opcodes, plus the reset and interrupt pseudo-opcodes
+func (op *OpCodes) Documented() []*OpCode {
+	var ocs []*OpCode
+	for code := 0; code < 256; code++ {
+		if oc := op.lookup[uint8(code)]; oc != nil && (!oc.Virtual || code == 0x02 || code == 0x12 || code == 0x22) {
+			ocs = append(ocs, oc)
+		}
+	}
+	return ocs
+}
+
+func opCodeAnchor(oc *OpCode) string {
+	return fmt.Sprintf("op-%02x", oc.OpCode)
+}
+func (op *OpCode) addressModeName() string {
+	if int(op.AddrMode) < len(AddressModeNames) && AddressModeNames[op.AddrMode] != "" {
+		return AddressModeNames[op.AddrMode]
+	}
+	return "-"
+}
+
+func (op *OpCodes) document(w docWriter) {
+	ocs := op.Documented()
+	w.heading(1, "", "Microcode reference")
+	w.paragraph(w.text("Generated from the built-in microcode. Each step lists the control lines that are active in each clock phase, for the flag variant with all flags clear. Other flag variants are listed where their microcode differs."))
+
+	w.heading(2, "", "Opcodes")
+	var rows [][]string
+	for _, oc := range ocs {
+		rows = append(rows, []string{w.link(fmt.Sprintf("$%02X", oc.OpCode), opCodeAnchor(oc)), oc.Name, oc.addressModeName(), fmt.Sprintf("%d", oc.StepCount(0)), fmt.Sprintf("%d", len(oc.Variants()))})
+	}
+	w.table([]string{"Opcode", "Name", "Mode", "Steps", "Variants"}, rows)
+
+	w.heading(2, "", "Control lines")
+	rows = nil
+	for index, mnemonic := range mnemonics {
+		bit := uint64(1) << (47 - index)
+		level := "high"
+		if ActiveLow(bit) {
+			level = "low"
+		}
+		rows = append(rows, []string{w.code("CL_" + mnemonic), fmt.Sprintf("%d", index / 8 + 1), level, w.text(lineDescriptions[index])})
+	}
+	w.table([]string{"Line", "EPROM byte", "Active", "Description"}, rows)
+
+	for _, oc := range ocs {
+		op.documentOpCode(w, oc)
+	}
+}
+
+func (op *OpCodes) documentOpCode(w docWriter, oc *OpCode) {
+	w.heading(2, opCodeAnchor(oc), w.text(fmt.Sprintf("$%02X %s %s", oc.OpCode, oc.Name, oc.addressModeName())))
+
+	facts := []string{
+		fmt.Sprintf("Syntax: %s", w.code(oc.Syntax)),
+		fmt.Sprintf("Addressing mode: %s", oc.addressModeName()),
+		fmt.Sprintf("Operands: %d byte(s)", oc.Operands),
+		fmt.Sprintf("Steps: %d", oc.StepCount(0)),
+	}
+	switch {
+	case oc.AddrMode == REL:
+		facts = append(facts, "Page cross: the branch takes an extra step when it is taken across a page boundary")
+	case oc.PageCross:
+		facts = append(facts, "Page cross: an extra step is taken when indexing crosses a page boundary")
+	default:
+		facts = append(facts, "Page cross: not affected")
+	}
+	if oc.Virtual {
+		facts = append(facts, "Pseudo-opcode: entered by the board rather than fetched")
+	}
+	for _, fact := range facts {
+		w.paragraph(fact)
+	}
+
+	variants := oc.Variants()
+	w.table([]string{"Step", "Phase", "Active lines"}, op.stepRows(w, oc, variants[0][0], nil))
+
+	for _, class := range variants[1:] {
+		rows := op.stepRows(w, oc, class[0], &oc.Lines[variants[0][0]])
+		if len(rows) == 0 {
+			continue
+		}
+		w.paragraph(w.text(fmt.Sprintf("Variant %s (flags %s) takes %d step(s) and differs:", oc.DescribeVariant(class), flagList(class), oc.StepCount(class[0]))))
+		w.table([]string{"Step", "Phase", "Active lines"}, rows)
+	}
+}
+
+// stepRows lists the active lines of each step and phase, only where they differ from base if one is given
+func (op *OpCodes) stepRows(w docWriter, oc *OpCode, flags uint8, base *[8][2]uint64) [][]string {
+	var rows [][]string
+	steps, baseSteps := oc.StepCount(flags), oc.StepCount(0)
+	if base != nil && baseSteps > steps {
+		steps = baseSteps
+	}
+	for step := uint8(0); step < steps; step++ {
+		for phase := uint8(0); phase < 2; phase++ {
+			if base != nil && step < baseSteps && step < oc.StepCount(flags) && base[step][phase] == oc.Lines[flags][step][phase] {
+				continue
+			}
+			active := ""
+			if step < oc.StepCount(flags) {
+				active = strings.Join(oc.DescribeLine(flags, step, phase, 64, " ", "", false), " ")
+			}
+			if active == "" {
+				active = "-"
+			}
+			rows = append(rows, []string{fmt.Sprintf("%d", step), fmt.Sprintf("Φ%d", phase + 1), w.code(active)})
+		}
+	}
+	return rows
+}
+
+func flagList(class []uint8) string {
+	var values []string
+	for _, flags := range class {
+		values = append(values, fmt.Sprintf("%04b", flags))
+	}
+	return strings.Join(values, ",")
+}
@@ -1405,6 +1405,35 @@ func (op *OpCode) DescribeLine(flags uint8, step uint8, clock uint8, groupSize i
 		lines = append(lines, strings.Join(collector, join))
 	}
 	return lines}
+// DescribeWord lists the lines that a control word activates for the given clock phase
+func DescribeWord(clock uint8, word uint64) string {
+	var active []string
+	for index := 0; index < len(mnemonics); index++ {
+		if (word ^ Defaults[clock]) & (uint64(1) << (47 - index)) != 0 {
+			active = append(active, mnemonics[index])
+		}
+	}
+	return strings.Join(active, " ")
+}
+// DescribeChange lists the lines that differ between two control words, prefixed
+// with + when the line became active and - when it became inactive
+func DescribeChange(clock uint8, before uint64, after uint64) string {
+	var changes []string
+	for index := 0; index < len(mnemonics); index++ {
+		bit := uint64(1) << (47 - index)
+		if (before ^ after) & bit == 0 {
+			continue
+		} else if (after ^ Defaults[clock]) & bit > 0 {
+			changes = append(changes, "+" + mnemonics[index])
+		} else {
+			changes = append(changes, "-" + mnemonics[index])
+		}
+	}
+	if len(changes) == 0 {
+		return "no change"
+	}
+	return strings.Join(changes, " ")
+}
 func (op *OpCode) uint64ToBinary(qword uint64, presetQword uint64, defaultQword uint64, lineColor string, clock uint8) string {
 
 	str1 := fmt.Sprintf("%s%%s%s", PresetChg, lineColor)
@@ -1434,6 +1463,17 @@ func (op *OpCode) uint64ToBinary(qword uint64, presetQword uint64, defaultQword
 	}
 	return bs
 }
+// ValidateWord applies ValidateLine to every line that differs between two control words
+func (op *OpCode) ValidateWord(step uint8, clock uint8, before uint64, after uint64) (string, bool) {
+	for bit := uint64(0); bit < 48; bit++ {
+		if (before ^ after) & (uint64(1) << bit) != 0 {
+			if str, ok := op.ValidateLine(step, clock, bit); !ok {
+				return str, false
+			}
+		}
+	}
+	return "Ok", true
+}
 func (op *OpCode) ValidateLine(step uint8, clock uint8, bit uint64 ) (string, bool) {
 	// Validation on which bits can be set when.
 	switch uint64(1 << bit) {
@@ -0,0 +1,86 @@
+package instructionSet
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseMnemonics turns a list of control lines such as "CL_AULA | CL_SBD0 | CL_SBD2",
+// as copied from the editor, into the mask of lines it asserts. "-" stands for none.
+func ParseMnemonics(text string) (uint64, error) {
+	var active uint64
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == '|' || r == ',' || r == '+' || r == ' ' || r == '\t' }) {
+		if field == "-" {
+			continue
+		}
+		bit, ok := LineBit(field)
+		if !ok {
+			return 0, fmt.Errorf("unknown control line '%s'", field)
+		}
+		active |= bit
+	}
+	return active, nil
+}
+
+// Word is the control word asserting exactly the given lines in a clock phase
+func Word(clock uint8, active uint64) uint64 {
+	return Defaults[clock] ^ active
+}
+
+// StepLines is one phase of copied microcode, positioned by step*2+phase
+type StepLines struct {
+	Index  int
+	Active uint64
+}
+
+var stepLine = regexp.MustCompile(`(?i)^\s*step\s+(\d+)\s+(?:Φ|phi|p)\s*([12])\s*:(.*)$`)
+
+// FormatSteps writes copied phases one per line, in the form read back by ParseSteps
+func FormatSteps(steps []StepLines) string {
+	var lines []string
+	for _, s := range steps {
+		active := DescribeWord(uint8(s.Index % 2), Word(uint8(s.Index % 2), s.Active))
+		if active == "" {
+			active = "-"
+		} else {
+			active = "CL_" + strings.ReplaceAll(active, " ", " | CL_")
+		}
+		lines = append(lines, fmt.Sprintf("step %d Φ%d: %s", s.Index / 2, s.Index % 2 + 1, active))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseSteps reads phases written by FormatSteps. A bare list of lines, with no
+// step prefix, is returned as a single phase at index -1.
+func ParseSteps(text string) ([]StepLines, error) {
+	var steps []StepLines
+	text = strings.TrimSpace(text)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		index, list := -1, line
+		if m := stepLine.FindStringSubmatch(line); m != nil {
+			step, _ := strconv.Atoi(m[1])
+			phase, _ := strconv.Atoi(m[2])
+			if step > 7 {
+				return nil, fmt.Errorf("step %d out of range", step)
+			}
+			index, list = step * 2 + phase - 1, m[3]
+		} else if len(steps) > 0 || strings.Contains(text, "\n") {
+			return nil, fmt.Errorf("expected 'step n Φp: lines' in '%s'", line)
+		}
+		active, err := ParseMnemonics(list)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, StepLines{Index: index, Active: active})
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("nothing to paste")
+	}
+	return steps, nil
+}
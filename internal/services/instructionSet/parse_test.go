@@ -0,0 +1,108 @@
+package instructionSet
+
+import (
+	"testing"
+)
+
+func lines(t *testing.T, names string) uint64 {
+	active, err := ParseMnemonics(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return active
+}
+
+func TestParseMnemonics(t *testing.T) {
+	tests := []struct {
+		text string
+		want uint64
+	}{
+		{"-", 0},
+		{"CL_AULA", CL_AULA},
+		{"CL_AULA | CL_SBD0 | CL_SBD2", CL_AULA | CL_SBD0 | CL_SBD2},
+		{"aula, sbd0 + CL_SBD2", CL_AULA | CL_SBD0 | CL_SBD2},
+		{"CL_CTMR\tCL_CENB", CL_CTMR | CL_CENB},
+	}
+	for _, test := range tests {
+		if got, err := ParseMnemonics(test.text); err != nil || got != test.want {
+			t.Errorf("ParseMnemonics(%q) = %012X, %v, want %012X", test.text, got, err, test.want)
+		}
+	}
+	if _, err := ParseMnemonics("CL_AULA | CL_NOPE"); err == nil {
+		t.Errorf("ParseMnemonics accepted an unknown line")
+	}
+}
+
+func TestFormatStepsRoundTrip(t *testing.T) {
+	tests := [][]StepLines{
+		{{Index: 0, Active: 0}},
+		{{Index: 3, Active: lines(t, "CL_AULA | CL_SBD0 | CL_SBD2")}},
+		{
+			{Index: 4, Active: lines(t, "CL_DBD0 CL_ALD1 CL_PCIN")},
+			{Index: 5, Active: lines(t, "CL_SBLA CL_FSVA")},
+			{Index: 6, Active: 0},
+			{Index: 7, Active: lines(t, "CL_CTMR")},
+		},
+		{{Index: 15, Active: lines(t, "CL_AHD0 CL_AHD1 CL_AHC1 CL_AHC0 CL_CENB")}},
+	}
+	for _, steps := range tests {
+		text := FormatSteps(steps)
+		got, err := ParseSteps(text)
+		if err != nil {
+			t.Errorf("ParseSteps(%q) failed: %v", text, err)
+			continue
+		}
+		if len(got) != len(steps) {
+			t.Errorf("ParseSteps(%q) = %v, want %v", text, got, steps)
+			continue
+		}
+		for i := range got {
+			if got[i] != steps[i] {
+				t.Errorf("ParseSteps(%q) = %v, want %v", text, got, steps)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSteps(t *testing.T) {
+	tests := []struct {
+		text string
+		want []StepLines
+	}{
+		{"CL_AULA | CL_SBD0", []StepLines{{Index: -1, Active: CL_AULA | CL_SBD0}}},
+		{"  step 2 phi1: CL_AULA\n\nSTEP 2 p2: -\n", []StepLines{{Index: 4, Active: CL_AULA}, {Index: 5, Active: 0}}},
+		{"step 7 Φ2: CL_CTMR", []StepLines{{Index: 15, Active: CL_CTMR}}},
+	}
+	for _, test := range tests {
+		got, err := ParseSteps(test.text)
+		if err != nil {
+			t.Errorf("ParseSteps(%q) failed: %v", test.text, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("ParseSteps(%q) = %v, want %v", test.text, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("ParseSteps(%q) = %v, want %v", test.text, got, test.want)
+				break
+			}
+		}
+	}
+
+	errors := []string{
+		"",
+		"step 8 Φ1: CL_AULA",
+		"step 1 Φ3: CL_AULA",
+		"step 1 Φ1: CL_NOPE",
+		"step 1 Φ1: CL_AULA\nCL_SBD0",
+		"CL_AULA\nCL_SBD0",
+	}
+	for _, text := range errors {
+		if got, err := ParseSteps(text); err == nil {
+			t.Errorf("ParseSteps(%q) = %v, want an error", text, got)
+		}
+	}
+}
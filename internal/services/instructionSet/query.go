@@ -0,0 +1,102 @@
+package instructionSet
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
+	"strings"
+)
+
+// Match is a step and phase of an opcode where a query holds, for the flag
+// values listed
+type Match struct {
+	OpCode *OpCode
+	Step   uint8
+	Phase  uint8
+	Flags  []uint8
+}
+
+func (m *Match) String() string {
+	return fmt.Sprintf("$%02X %-3s %-3s step %d Φ%d  %s", m.OpCode.OpCode, m.OpCode.Name, m.OpCode.addressModeName(), m.Step, m.Phase + 1, m.OpCode.DescribeVariant(m.Flags))
+}
+
+// location is the expression environment for one opcode, flags, step and phase. Control
+// lines evaluate to 1 when asserted, phi1/phi2 and the flags N, V, Z and C to 1 when they
+// hold, while step, phase, flags, opcode and mode give their values and the addressing
+// mode names their numbers, so "SPLD and phi2" or "mode == ABX and not C" can be asked.
+type location struct {
+	oc    *OpCode
+	flags uint8
+	step  uint8
+	phase uint8
+}
+
+func (l *location) Lookup(name string) (int64, bool) {
+	if bit, ok := LineBit(name); ok {
+		return boolean((l.oc.Lines[l.flags][l.step][l.phase] ^ Defaults[l.phase]) & bit != 0), true
+	}
+	for _, f := range flagBits {
+		if strings.EqualFold(name, f.name) {
+			return boolean(l.flags & f.bit != 0), true
+		}
+	}
+	switch strings.ToLower(name) {
+	case "phi1":
+		return boolean(l.phase == PHI1), true
+	case "phi2":
+		return boolean(l.phase == PHI2), true
+	case "phase":
+		return int64(l.phase) + 1, true
+	case "step":
+		return int64(l.step), true
+	case "flags":
+		return int64(l.flags), true
+	case "opcode":
+		return int64(l.oc.OpCode), true
+	case "mode":
+		return int64(l.oc.AddrMode), true
+	}
+	for mode, modeName := range AddressModeNames {
+		if modeName != "" && strings.EqualFold(name, modeName) {
+			return int64(mode), true
+		}
+	}
+	return 0, false
+}
+func boolean(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Query finds every step and phase of the instruction set where the expression holds,
+// merging the flag values that match at the same place
+func (op *OpCodes) Query(text string) ([]*Match, error) {
+	node, err := expression.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Match
+	for _, oc := range op.Documented() {
+		for step := uint8(0); step < 8; step++ {
+			for phase := uint8(0); phase < 2; phase++ {
+				var flags []uint8
+				for f := uint8(0); f < 16; f++ {
+					if step >= oc.StepCount(f) {
+						continue
+					}
+					found, err := expression.IsTrue(node, &location{oc: oc, flags: f, step: step, phase: phase})
+					if err != nil {
+						return nil, err
+					} else if found {
+						flags = append(flags, f)
+					}
+				}
+				if len(flags) > 0 {
+					matches = append(matches, &Match{OpCode: oc, Step: step, Phase: phase, Flags: flags})
+				}
+			}
+		}
+	}
+	return matches, nil
+}
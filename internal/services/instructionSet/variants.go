@@ -0,0 +1,75 @@
+package instructionSet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bits of the flags index used to select microcode
+var flagBits = []struct {
+	bit  uint8
+	name string
+}{{8, "N"}, {4, "V"}, {2, "Z"}, {1, "C"}}
+
+// Variants groups the 16 flag values into classes whose microcode is identical,
+// in order of the lowest flag value in each class
+func (op *OpCode) Variants() [][]uint8 {
+	var classes [][]uint8
+	for flags := uint8(0); flags < 16; flags++ {
+		found := false
+		for i, class := range classes {
+			if op.Lines[class[0]] == op.Lines[flags] {
+				classes[i] = append(class, flags)
+				found = true
+				break
+			}
+		}
+		if !found {
+			classes = append(classes, []uint8{flags})
+		}
+	}
+	return classes
+}
+
+// StepCount is the number of steps executed for a flag value, up to the
+// step whose second phase resets the timer
+func (op *OpCode) StepCount(flags uint8) uint8 {
+	for step := uint8(0); step < 8; step++ {
+		if (op.Lines[flags][step][PHI2] ^ Defaults[PHI2]) & CL_CTMR != 0 {
+			return step + 1
+		}
+	}
+	return 8
+}
+
+// DescribeVariant names a class of flag values by the flags that are fixed
+// across it. The carry selects the page-cross variant of indexed modes.
+func (op *OpCode) DescribeVariant(class []uint8) string {
+	var fixed []string
+	for _, f := range flagBits {
+		value := class[0] & f.bit
+		same := true
+		for _, flags := range class {
+			if flags & f.bit != value {
+				same = false
+				break
+			}
+		}
+		if !same {
+			continue
+		}
+		if f.bit == 1 && op.PageCross && op.AddrMode != REL {
+			if value != 0 {
+				fixed = append(fixed, "page-cross")
+			} else {
+				fixed = append(fixed, "no page-cross")
+			}
+		} else {
+			fixed = append(fixed, fmt.Sprintf("%s=%d", f.name, value / f.bit))
+		}
+	}
+	if len(fixed) == 0 {
+		return "all flags"
+	}
+	return strings.Join(fixed, " ")
+}
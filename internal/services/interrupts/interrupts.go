@@ -0,0 +1,177 @@
+package interrupts
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"strconv"
+	"strings"
+)
+
+// Line identifies an interrupt line by the byte used for it in the 'Q' serial command
+type Line byte
+
+const (
+	IRQ   Line = 'i'
+	NMI   Line = 'n'
+	RESET Line = 'r'
+)
+
+// pulseCycles is how long a one-shot assert holds a line
+const pulseCycles = 2
+
+func (l Line) String() string {
+	switch l {
+	case IRQ:
+		return "IRQ"
+	case NMI:
+		return "NMI"
+	case RESET:
+		return "RESET"
+	}
+	return fmt.Sprintf("line %q", byte(l))
+}
+
+// Event asserts a line at a cycle, for a number of cycles, and optionally repeats
+type Event struct {
+	Line     Line
+	At       uint64
+	Every    uint64
+	Duration uint64
+	text     string
+}
+
+func (e *Event) String() string {
+	return e.text
+}
+func (e *Event) due(cycle uint64) bool {
+	if cycle < e.At {
+		return false
+	} else if e.Every == 0 {
+		return cycle == e.At
+	}
+	return (cycle - e.At) % e.Every == 0
+}
+
+// Parse reads a schedule entry such as "irq at 1200 for 3" or "nmi every 10000".
+// at defaults to the first occurrence of every, and for defaults to a short pulse.
+func Parse(text string) (*Event, error) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	e := &Event{Duration: pulseCycles, text: strings.Join(fields, " ")}
+	switch fields[0] {
+	case "irq":
+		e.Line = IRQ
+	case "nmi":
+		e.Line = NMI
+	case "reset", "res":
+		e.Line = RESET
+	default:
+		return nil, fmt.Errorf("unknown line '%s'", fields[0])
+	}
+
+	hasAt := false
+	for i := 1; i < len(fields); i += 2 {
+		if i + 1 >= len(fields) {
+			return nil, fmt.Errorf("'%s' needs a number of cycles", fields[i])
+		}
+		value, err := strconv.ParseUint(fields[i+1], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cycle count '%s'", fields[i+1])
+		}
+		switch fields[i] {
+		case "at":
+			e.At, hasAt = value, true
+		case "every":
+			e.Every = value
+		case "for":
+			e.Duration = value
+		default:
+			return nil, fmt.Errorf("unexpected '%s'", fields[i])
+		}
+	}
+	if !hasAt && e.Every == 0 {
+		return nil, fmt.Errorf("schedule needs 'at' or 'every'")
+	} else if !hasAt {
+		e.At = e.Every
+	}
+	if e.Duration == 0 {
+		e.Duration = 1
+	}
+	return e, nil
+}
+
+// Scheduler asserts interrupt lines for one-shot pulses and scheduled events,
+// releasing each once its duration has passed. The lines are only driven on the
+// board, through the 'Q' serial command: there is no simulator to drive them in,
+// so nothing is asserted while no board is attached.
+type Scheduler struct {
+	events  []*Event
+	release map[Line]uint64
+	cycle   uint64
+	assert  func(line Line, asserted bool) bool
+	log     *logging.Log
+}
+
+func New(log *logging.Log, assert func(line Line, asserted bool) bool) *Scheduler {
+	return &Scheduler{
+		release: map[Line]uint64{},
+		assert:  assert,
+		log:     log,
+	}
+}
+
+func (s *Scheduler) Add(text string) error {
+	e, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+func (s *Scheduler) Events() []*Event {
+	return s.events
+}
+func (s *Scheduler) Clear() {
+	s.events = nil
+}
+
+// Pulse asserts a line now, for a couple of cycles
+func (s *Scheduler) Pulse(line Line) {
+	s.hold(line, pulseCycles)
+}
+func (s *Scheduler) hold(line Line, cycles uint64) {
+	if s.assert(line, true) {
+		s.release[line] = s.cycle + cycles
+		s.log.Infof("%s asserted for %d cycle(s)", line, cycles)
+	} else {
+		s.log.Warnf("Failed to assert %s", line)
+	}
+}
+
+// Cycle is called at the start of every clock cycle
+func (s *Scheduler) Cycle(cycle uint64) {
+	if cycle < s.cycle {
+		// The counter restarted, typically after a reset, so keep the remaining hold times
+		for line, at := range s.release {
+			s.release[line] = cycle + at - s.cycle
+		}
+	}
+	s.cycle = cycle
+	for line, at := range s.release {
+		if cycle >= at {
+			delete(s.release, line)
+			if !s.assert(line, false) {
+				s.log.Warnf("Failed to release %s", line)
+			}
+		}
+	}
+	for _, e := range s.events {
+		if e.due(cycle) {
+			s.log.Infof("Scheduled %s at cycle %d", e, cycle)
+			s.hold(e.Line, e.Duration)
+		}
+	}
+}
+
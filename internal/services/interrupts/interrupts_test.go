@@ -0,0 +1,60 @@
+package interrupts
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want Event
+	}{
+		{"irq at 1200 for 3", Event{Line: IRQ, At: 1200, Duration: 3}},
+		{"nmi every 10000", Event{Line: NMI, At: 10000, Every: 10000, Duration: pulseCycles}},
+		{"NMI every 100 at 5", Event{Line: NMI, At: 5, Every: 100, Duration: pulseCycles}},
+		{"res at 0x10", Event{Line: RESET, At: 16, Duration: pulseCycles}},
+		{"reset at 0", Event{Line: RESET, At: 0, Duration: pulseCycles}},
+		{"irq at 7 for 0", Event{Line: IRQ, At: 7, Duration: 1}},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.text, err)
+			continue
+		}
+		if got.Line != test.want.Line || got.At != test.want.At || got.Every != test.want.Every || got.Duration != test.want.Duration {
+			t.Errorf("Parse(%q) = %s at %d every %d for %d, want %s at %d every %d for %d", test.text,
+				got.Line, got.At, got.Every, got.Duration, test.want.Line, test.want.At, test.want.Every, test.want.Duration)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"brk at 10",
+		"irq",
+		"irq for 3",
+		"irq at",
+		"irq at ten",
+		"irq at -1",
+		"irq after 10",
+	}
+	for _, text := range tests {
+		if e, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) = %q, want an error", text, e)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	e, err := Parse("irq every 10 at 5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cycle, want := range map[uint64]bool{0: false, 4: false, 5: true, 6: false, 15: true, 25: true, 26: false} {
+		if got := e.due(cycle); got != want {
+			t.Errorf("due(%d) = %v, want %v", cycle, got, want)
+		}
+	}
+}
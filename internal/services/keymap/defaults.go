@@ -0,0 +1,172 @@
+package keymap
+
+// binding is a default key binding. Keys are separated by spaces.
+type binding struct {
+	scope       string
+	action      string
+	keys        string
+	description string
+}
+
+var defaults = []binding{
+	{Global,  "help",              "h",      "Show this page"},
+	{Global,  "quit",              "q",      "Quit"},
+	{Global,  "command",           ":",      "Command line"},
+	{Global,  "log_history",       "l",      "Log history"},
+	{Global,  "debug_on",          "d",      "Debug enabled"},
+	{Global,  "debug_off",         "D",      "Debug disabled"},
+	{Global,  "ports",             "p",      "Show ports"},
+	{Global,  "traffic",           "t",      "Serial traffic"},
+	{Global,  "read_address",      "a",      "Read address"},
+	{Global,  "export",            "e",      "Export microcode"},
+	{Global,  "undo",              "ctrl+z", "Undo edit"},
+	{Global,  "redo",              "ctrl+y", "Redo edit"},
+	{Global,  "copy_lines",        "c",      "Copy op lines"},
+	{Global,  "copy_all_lines",    "C",      "Copy all lines"},
+	{Global,  "mark_steps",        "v",      "Mark steps"},
+	{Global,  "copy_steps",        "y",      "Copy steps"},
+	{Global,  "paste_steps",       "x",      "Paste steps"},
+	{Global,  "edit_scope",        "S",      "Edit scope"},
+	{Global,  "line_editor",       "L",      "Ctrl Line editor"},
+	{Global,  "memory_editor",     "M",      "Memory editor"},
+	{Global,  "bus_editor",        "B",      "Bus editor"},
+	{Global,  "flags_editor",      "F",      "Flags editor"},
+	{Global,  "next_editor",       "tab",    "Next editor"},
+	{Global,  "toggle_flags",      "f",      "Toggle flag usage"},
+	{Global,  "sync_flags",        "s",      "Sync dev flags"},
+	{Global,  "toggle_breakpoint", "b",      "Toggle breakpoint"},
+	{Global,  "breakpoints",       "k",      "Break/watch list"},
+	{Global,  "step_phase",        "n",      "Step phase"},
+	{Global,  "step_cycle",        "N",      "Step cycle"},
+	{Global,  "step_instruction",  "i",      "Step instruction"},
+	{Global,  "step_over",         "o",      "Step over"},
+	{Global,  "step_out",          "O",      "Step out"},
+	{Global,  "run_to_cursor",     "G",      "Run to cursor"},
+	{Global,  "run",               "r",      "Run/pause"},
+	{Global,  "run_cycles",        "R",      "Run N cycles"},
+	{Global,  "clock_faster",      "+",      "Clock faster"},
+	{Global,  "clock_slower",      "-",      "Clock slower"},
+	{Global,  "pulse_irq",         "I",      "Pulse IRQ"},
+	{Global,  "pulse_nmi",         "U",      "Pulse NMI"},
+	{Global,  "pulse_reset",       "X",      "Pulse RESET"},
+	{Global,  "profiler",          "P",      "Profiler"},
+	{Global,  "coverage",          "V",      "Coverage"},
+	{Global,  "waveform",          "W",      "Waveform"},
+	{Global,  "datapath",          "A",      "Datapath"},
+	{Global,  "query",             "Q",      "Microcode query"},
+
+	{Lines,   "up",                "up",     "Previous phase"},
+	{Lines,   "down",              "down",   "Next phase"},
+	{Lines,   "left",              "left",   "Previous line"},
+	{Lines,   "right",             "right",  "Next line"},
+	{Lines,   "deactivate",        "0",      "Deactivate line"},
+	{Lines,   "activate",          "1",      "Activate line"},
+	{Lines,   "toggle",            "space",  "Toggle line"},
+	{Lines,   "reset",             "delete", "Reset line"},
+
+	{Bus,     "up",                "up",     "Previous bus"},
+	{Bus,     "down",              "down",   "Next bus"},
+	{Bus,     "left",              "left",   "Previous source"},
+	{Bus,     "right",             "right",  "Next source"},
+
+	{Memory,  "up",                "up",     "Cursor up"},
+	{Memory,  "down",              "down",   "Cursor down"},
+	{Memory,  "left",              "left",   "Cursor left"},
+	{Memory,  "right",             "right",  "Cursor right"},
+	{Memory,  "page_up",           "pgup [", "Page up"},
+	{Memory,  "page_down",         "pgdn ]", "Page down"},
+	{Memory,  "edit",              "enter delete", "Edit byte"},
+	{Memory,  "cancel",            "esc",    "Cancel edit"},
+	{Memory,  "goto",              "g",      "Goto address"},
+	{Memory,  "search",            "/",      "Search memory"},
+	{Memory,  "fill",              "=",      "Fill range"},
+	{Memory,  "copy",              "m",      "Copy range"},
+	{Memory,  "follow",            ".",      "Follow bus"},
+	{Memory,  "ascii",             "\"",     "ASCII view"},
+	{Memory,  "toggle_breakpoint", "b",      "Breakpoint"},
+	{Memory,  "watch",             "w",      "Watchpoint"},
+	{Memory,  "break_if",          "?",      "Break if..."},
+
+	{Flags,   "set",               "up 1",   "Set flag"},
+	{Flags,   "clear",             "down 0", "Clear flag"},
+	{Flags,   "left",              "left",   "Previous flag"},
+	{Flags,   "right",             "right",  "Next flag"},
+	{Flags,   "toggle",            "space enter", "Toggle flag"},
+
+	{History, "up",                "up",     "Scroll up"},
+	{History, "down",              "down",   "Scroll down"},
+	{History, "page_up",           "q",      "Page up"},
+	{History, "page_down",         "a",      "Page down"},
+	{History, "clear",             "c",      "Clear log"},
+	{History, "silence",           "s",      "Toggle bell"},
+
+	{Breakpoints, "up",           "up",     "Scroll up"},
+	{Breakpoints, "down",         "down",   "Scroll down"},
+	{Breakpoints, "page_up",      "pgup",   "Page up"},
+	{Breakpoints, "page_down",    "pgdn",   "Page down"},
+	{Breakpoints, "toggle",       "space",  "Enable/disable"},
+	{Breakpoints, "delete",       "d delete", "Delete"},
+	{Breakpoints, "label",        "n",      "Label"},
+	{Breakpoints, "goto",         "enter",  "Jump to"},
+
+	{Profiler,    "up",           "up",     "Scroll up"},
+	{Profiler,    "down",         "down",   "Scroll down"},
+	{Profiler,    "page_up",      "pgup",   "Page up"},
+	{Profiler,    "page_down",    "pgdn",   "Page down"},
+	{Profiler,    "sort_cycles",  "1",      "Sort by cycles"},
+	{Profiler,    "sort_calls",   "2",      "Sort by calls"},
+	{Profiler,    "sort_average", "3",      "Sort by average"},
+	{Profiler,    "view",         "v",      "Address/opcode"},
+	{Profiler,    "export",       "x",      "Export"},
+	{Profiler,    "reset",        "z",      "Reset"},
+
+	{Coverage,    "up",           "up",     "Scroll up"},
+	{Coverage,    "down",         "down",   "Scroll down"},
+	{Coverage,    "page_up",      "pgup",   "Page up"},
+	{Coverage,    "page_down",    "pgdn",   "Page down"},
+	{Coverage,    "export",       "x",      "Export"},
+	{Coverage,    "reset",        "z",      "Reset"},
+
+	{Waveform,    "up",           "up",     "Previous line"},
+	{Waveform,    "down",         "down",   "Next line"},
+	{Waveform,    "left",         "left",   "Scroll back"},
+	{Waveform,    "right",        "right",  "Scroll on"},
+	{Waveform,    "page_up",      "pgup",   "Page back"},
+	{Waveform,    "page_down",    "pgdn",   "Page on"},
+	{Waveform,    "zoom_in",      "+",      "Zoom in"},
+	{Waveform,    "zoom_out",     "-",      "Zoom out"},
+	{Waveform,    "pick_lines",   "l",      "Pick lines"},
+	{Waveform,    "drop_line",    "d",      "Drop line"},
+	{Waveform,    "view",         "v",      "Opcode/trace"},
+	{Waveform,    "asserted",     "a",      "Asserted/levels"},
+	{Waveform,    "clear",        "c",      "Clear trace"},
+	{Waveform,    "export",       "x",      "Export VCD"},
+
+	{LinePicker,  "up",           "up",     "Line above"},
+	{LinePicker,  "down",         "down",   "Line below"},
+	{LinePicker,  "left",         "left",   "Previous line"},
+	{LinePicker,  "right",        "right",  "Next line"},
+	{LinePicker,  "toggle",       "space",  "Toggle line"},
+	{LinePicker,  "done",         "enter l esc", "Done"},
+
+	{Datapath,    "edit",         "e",      "Executing/edit step"},
+
+	{Traffic,     "up",           "up",     "Scroll up"},
+	{Traffic,     "down",         "down",   "Scroll down"},
+	{Traffic,     "page_up",      "pgup",   "Page up"},
+	{Traffic,     "page_down",    "pgdn",   "Page down"},
+	{Traffic,     "follow",       "e",      "Follow"},
+	{Traffic,     "clear",        "c",      "Clear"},
+
+	{Ports,       "up",           "up",     "Previous port"},
+	{Ports,       "down",         "down",   "Next port"},
+	{Ports,       "select",       "enter",  "Select port"},
+	{Ports,       "refresh",      "f",      "Refresh list"},
+	{Ports,       "retry",        "r",      "Retry refused port"},
+
+	{BulkEdit,    "up",           "up",     "Scroll up"},
+	{BulkEdit,    "down",         "down",   "Scroll down"},
+	{BulkEdit,    "page_up",      "pgup",   "Page up"},
+	{BulkEdit,    "page_down",    "pgdn",   "Page down"},
+	{BulkEdit,    "apply",        "y Y",    "Apply"},
+}
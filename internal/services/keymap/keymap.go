@@ -0,0 +1,273 @@
+package keymap
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"sort"
+	"strings"
+)
+
+// Scopes. While an editor has the cursor its scope is checked before Global,
+// so an editor binding shadows a global one on the same key. New reports it.
+// A page's scope replaces Global while the page is showing, and any key it
+// doesn't bind closes the page.
+const (
+	Global      = "global"
+	Lines       = "lines"
+	Bus         = "bus"
+	Memory      = "memory"
+	Flags       = "flags"
+	History     = "history"
+	Breakpoints = "breakpoints"
+	Profiler    = "profiler"
+	Coverage    = "coverage"
+	Waveform    = "waveform"
+	LinePicker  = "line_picker"
+	Datapath    = "datapath"
+	Traffic     = "traffic"
+	Ports       = "ports"
+	BulkEdit    = "bulk_edit"
+)
+
+// Scopes lists every scope in the order shown on the help page
+var Scopes = []string{Global, Lines, Bus, Memory, Flags, History, Breakpoints, Profiler, Coverage, Waveform, LinePicker, Datapath, Traffic, Ports, BulkEdit}
+
+// editors are the scopes checked before Global
+var editors = []string{Lines, Bus, Memory, Flags}
+
+var scopeTitles = map[string]string{
+	Global:      "Key mappings",
+	Lines:       "Control line editor",
+	Bus:         "Bus editor",
+	Memory:      "Memory editor",
+	Flags:       "Flags editor",
+	History:     "Notification log",
+	Breakpoints: "Break/watch list",
+	Profiler:    "Profiler",
+	Coverage:    "Coverage",
+	Waveform:    "Waveform",
+	LinePicker:  "Waveform line picker",
+	Datapath:    "Datapath",
+	Traffic:     "Serial traffic",
+	Ports:       "Ports",
+	BulkEdit:    "Bulk edit preview",
+}
+
+// Key is a key press as read by the driver: a character, or a cursor key code
+type Key struct {
+	Ascii   int
+	KeyCode int
+}
+
+var names = map[string]Key{
+	"up":     {KeyCode: display.CursorUp},
+	"down":   {KeyCode: display.CursorDown},
+	"left":   {KeyCode: display.CursorLeft},
+	"right":  {KeyCode: display.CursorRight},
+	"pgup":   {KeyCode: display.PageUp},
+	"pgdn":   {KeyCode: display.PageDown},
+	"tab":    {Ascii: '\t'},
+	"enter":  {Ascii: 13},
+	"esc":    {Ascii: 27},
+	"space":  {Ascii: ' '},
+	"delete": {Ascii: 127},
+}
+
+// ParseKey reads a key name: a single character, one of up, down, left, right,
+// pgup, pgdn, tab, enter, esc, space and delete, or ctrl+a to ctrl+z
+func ParseKey(name string) (Key, error) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	if key, ok := names[lower]; ok {
+		return key, nil
+	} else if len(lower) == 6 && strings.HasPrefix(lower, "ctrl+") && lower[5] >= 'a' && lower[5] <= 'z' {
+		return Key{Ascii: int(lower[5] - 'a' + 1)}, nil
+	} else if len(name) == 1 && name[0] > ' ' && name[0] < 127 {
+		return Key{Ascii: int(name[0])}, nil
+	}
+	return Key{}, fmt.Errorf("unknown key '%s'", name)
+}
+func (k Key) String() string {
+	for name, key := range names {
+		if key == k {
+			return name
+		}
+	}
+	if k.KeyCode == 0 && k.Ascii >= 1 && k.Ascii <= 26 {
+		return fmt.Sprintf("^%c", 'A' + k.Ascii - 1)
+	}
+	return string(rune(k.Ascii))
+}
+
+// Binding is a named action and the keys that run it
+type Binding struct {
+	Scope       string
+	Action      string
+	Description string
+	Keys        []Key
+	custom      bool
+}
+
+// KeyNames lists the binding's keys for the help page
+func (b *Binding) KeyNames() string {
+	var keys []string
+	for _, key := range b.Keys {
+		keys = append(keys, key.String())
+	}
+	return strings.Join(keys, " ")
+}
+
+// Keymap resolves key presses to actions. Keys set in config.yaml replace an
+// action's defaults and take priority over defaults in the same scope.
+type Keymap struct {
+	bindings []*Binding
+	lookup   map[string]map[Key]*Binding
+	errors   []string
+}
+
+// New starts from the default bindings and applies the keys section of config.yaml,
+// which maps scope to action to a key or list of keys
+func New(custom map[string]map[string][]string) *Keymap {
+	k := &Keymap{lookup: map[string]map[Key]*Binding{}}
+	for _, d := range defaults {
+		b := &Binding{Scope: d.scope, Action: d.action, Description: d.description}
+		for _, name := range strings.Fields(d.keys) {
+			key, err := ParseKey(name)
+			if err != nil {
+				panic(err)
+			}
+			b.Keys = append(b.Keys, key)
+		}
+		k.bindings = append(k.bindings, b)
+	}
+
+	scopes := make([]string, 0, len(custom))
+	for scope := range custom {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		actions := make([]string, 0, len(custom[scope]))
+		for action := range custom[scope] {
+			actions = append(actions, action)
+		}
+		sort.Strings(actions)
+		for _, action := range actions {
+			k.customise(scope, action, custom[scope][action])
+		}
+	}
+
+	// Custom bindings claim their keys first, so a default using the same key loses it
+	for _, custom := range []bool{true, false} {
+		for _, b := range k.bindings {
+			if b.custom == custom {
+				k.claim(b)
+			}
+		}
+	}
+	k.shadowed()
+	return k
+}
+func (k *Keymap) customise(scope string, action string, keys []string) {
+	b := k.binding(strings.ToLower(scope), strings.ToLower(action))
+	if b == nil {
+		k.errors = append(k.errors, fmt.Sprintf("Key binding for unknown action %s.%s", scope, action))
+		return
+	}
+	b.Keys, b.custom = nil, true
+	for _, name := range keys {
+		if key, err := ParseKey(name); err != nil {
+			k.errors = append(k.errors, fmt.Sprintf("Key binding %s.%s: %v", scope, action, err))
+		} else {
+			b.Keys = append(b.Keys, key)
+		}
+	}
+}
+func (k *Keymap) claim(b *Binding) {
+	if k.lookup[b.Scope] == nil {
+		k.lookup[b.Scope] = map[Key]*Binding{}
+	}
+	var keys []Key
+	for _, key := range b.Keys {
+		if other, ok := k.lookup[b.Scope][key]; ok && other != b {
+			k.errors = append(k.errors, fmt.Sprintf("Key '%s' in %s is bound to both %s and %s, keeping %s", key, b.Scope, other.Action, b.Action, other.Action))
+			continue
+		}
+		k.lookup[b.Scope][key] = b
+		keys = append(keys, key)
+	}
+	b.Keys = keys
+}
+
+// shadowed reports editor keys that hide a global action while that editor has the
+// cursor. An editor's own version of the global action, such as the memory editor
+// toggling a breakpoint at its cursor, isn't reported.
+func (k *Keymap) shadowed() {
+	for _, scope := range editors {
+		for _, b := range k.Bindings(scope) {
+			for _, key := range b.Keys {
+				if global, ok := k.lookup[Global][key]; ok && global.Action != b.Action {
+					k.errors = append(k.errors, fmt.Sprintf("Key '%s' for %s.%s shadows global %s in the %s", key, scope, b.Action, global.Action, strings.ToLower(Title(scope))))
+				}
+			}
+		}
+	}
+}
+func (k *Keymap) binding(scope string, action string) *Binding {
+	for _, b := range k.bindings {
+		if b.Scope == scope && b.Action == action {
+			return b
+		}
+	}
+	return nil
+}
+
+// Action names the action bound to a key press in a scope, or "" if there is none
+func (k *Keymap) Action(scope string, input common.Input) string {
+	if b, ok := k.lookup[scope][Key{Ascii: input.Ascii, KeyCode: input.KeyCode}]; ok {
+		return b.Action
+	}
+	return ""
+}
+
+// Bindings lists a scope's actions in their default order
+func (k *Keymap) Bindings(scope string) []*Binding {
+	var bindings []*Binding
+	for _, b := range k.bindings {
+		if b.Scope == scope {
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+// KeyNames lists the keys bound to an action, for prompts and footers
+func (k *Keymap) KeyNames(scope string, action string) string {
+	if b := k.binding(scope, action); b != nil {
+		return b.KeyNames()
+	}
+	return ""
+}
+
+// Help describes a page's actions for its footer, such as "x export, z reset",
+// leaving out actions with no keys
+func (k *Keymap) Help(scope string, actions ...string) string {
+	var help []string
+	for _, action := range actions {
+		if b := k.binding(scope, action); b != nil && len(b.Keys) > 0 {
+			help = append(help, b.KeyNames() + " " + strings.ToLower(b.Description))
+		}
+	}
+	return strings.Join(help, ", ")
+}
+
+// Errors reports bad entries in config.yaml, keys bound twice in the same scope
+// and editor keys that shadow global ones
+func (k *Keymap) Errors() []string {
+	return k.errors
+}
+
+// Title is the heading used for a scope on the help page
+func Title(scope string) string {
+	return scopeTitles[scope]
+}
@@ -0,0 +1,144 @@
+package keymap
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want Key
+		ok   bool
+	}{
+		{"q", Key{Ascii: 'q'}, true},
+		{"Q", Key{Ascii: 'Q'}, true},
+		{"up", Key{KeyCode: display.CursorUp}, true},
+		{"PgDn", Key{KeyCode: display.PageDown}, true},
+		{"space", Key{Ascii: ' '}, true},
+		{"ctrl+b", Key{Ascii: 2}, true},
+		{"CTRL+Z", Key{Ascii: 26}, true},
+		{"ctrl+1", Key{}, false},
+		{"ctrl+zz", Key{}, false},
+		{"f1", Key{}, false},
+		{"", Key{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseKey(test.name)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("ParseKey(%q) = %+v, %v, want %+v ok %v", test.name, got, err, test.want, test.ok)
+		}
+	}
+}
+
+func TestNewReportsConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		custom map[string]map[string][]string
+		errors []string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:   "unknown action",
+			custom: map[string]map[string][]string{"global": {"nope": {"z"}}},
+			errors: []string{"Key binding for unknown action global.nope"},
+		},
+		{
+			name:   "unknown key",
+			custom: map[string]map[string][]string{"global": {"quit": {"ctrl+zz"}}},
+			errors: []string{"Key binding global.quit: unknown key 'ctrl+zz'"},
+		},
+		{
+			name:   "custom key beats a default",
+			custom: map[string]map[string][]string{"global": {"quit": {"h"}}},
+			errors: []string{"Key 'h' in global is bound to both quit and help, keeping quit"},
+		},
+		{
+			name:   "two custom keys",
+			custom: map[string]map[string][]string{"global": {"quit": {"z"}, "help": {"z"}}},
+			errors: []string{"Key 'z' in global is bound to both help and quit, keeping help"},
+		},
+		{
+			name:   "editor key shadows a global",
+			custom: map[string]map[string][]string{"memory": {"goto": {"q"}}},
+			errors: []string{"Key 'q' for memory.goto shadows global quit in the memory editor"},
+		},
+		{
+			name:   "global key shadowed by an editor",
+			custom: map[string]map[string][]string{"global": {"quit": {"1"}}},
+			errors: []string{
+				"Key '1' for lines.activate shadows global quit in the control line editor",
+				"Key '1' for flags.set shadows global quit in the flags editor",
+			},
+		},
+		{
+			name:   "editor key for another action than the global",
+			custom: map[string]map[string][]string{"memory": {"goto": {"b"}}},
+			errors: []string{
+				"Key 'b' in memory is bound to both goto and toggle_breakpoint, keeping goto",
+				"Key 'b' for memory.goto shadows global toggle_breakpoint in the memory editor",
+			},
+		},
+		{
+			name:   "log history is not an editor",
+			custom: map[string]map[string][]string{"history": {"clear": {"h"}}},
+		},
+		{
+			name:   "page key bound twice",
+			custom: map[string]map[string][]string{"profiler": {"export": {"1"}}},
+			errors: []string{"Key '1' in profiler is bound to both export and sort_cycles, keeping export"},
+		},
+		{
+			name:   "pages replace global keys",
+			custom: map[string]map[string][]string{"waveform": {"export": {"q"}}},
+		},
+		{
+			name:   "same key in different editors",
+			custom: map[string]map[string][]string{"bus": {"up": {"up", "g"}}},
+		},
+	}
+	for _, test := range tests {
+		k := New(test.custom)
+		got := k.Errors()
+		if strings.Join(got, "\n") != strings.Join(test.errors, "\n") {
+			t.Errorf("%s: errors %q, want %q", test.name, got, test.errors)
+		}
+	}
+}
+
+func TestCustomKeys(t *testing.T) {
+	k := New(map[string]map[string][]string{"Global": {"Quit": {"Z", "ctrl+q"}, "help": {"q"}}})
+	tests := []struct {
+		scope string
+		input common.Input
+		want  string
+	}{
+		{Global, common.Input{Ascii: 'Z'}, "quit"},
+		{Global, common.Input{Ascii: 17}, "quit"},
+		{Global, common.Input{Ascii: 'q'}, "help"},
+		{Global, common.Input{Ascii: 'h'}, ""},
+		{Memory, common.Input{Ascii: 'g'}, "goto"},
+		{Memory, common.Input{KeyCode: display.PageUp}, "page_up"},
+		{Memory, common.Input{Ascii: '['}, "page_up"},
+		{Profiler, common.Input{Ascii: '2'}, "sort_calls"},
+		{Profiler, common.Input{Ascii: 'q'}, ""},
+	}
+	if errors := k.Errors(); len(errors) != 0 {
+		t.Errorf("unexpected errors %q", errors)
+	}
+	for _, test := range tests {
+		if got := k.Action(test.scope, test.input); got != test.want {
+			t.Errorf("Action(%s, %+v) = %q, want %q", test.scope, test.input, got, test.want)
+		}
+	}
+	if got := k.KeyNames(Global, "quit"); got != "Z ^Q" {
+		t.Errorf("KeyNames(quit) = %q, want %q", got, "Z ^Q")
+	}
+	if got, want := k.Help(Coverage, "export", "reset"), "x export, z reset"; got != want {
+		t.Errorf("Help(coverage) = %q, want %q", got, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"sync"
 )
 
@@ -14,6 +15,7 @@ type History struct {
 	offsetMax int
 	pageSize  int
 	silence   bool
+	keys      *keymap.Keymap
 	sync      sync.Mutex
 }
 
@@ -41,72 +43,57 @@ func (h *History) Draw(t *display.Terminal, connected bool, initialize bool) {
 			t.PrintAt(1, row, h.messages[max - h.offset - row + 1])
 		}
 	}
-	t.PrintAtf(1, t.Rows(), "%sPress 'c' to clear, arrows to scroll, 'q/a' to page up/down, any other key to exit%s", common.Yellow, common.Reset)
+	t.PrintAtf(1, t.Rows(), "%sPress '%s' to clear, %s/%s to scroll, '%s/%s' to page up/down, any other key to exit%s%s", common.Yellow, h.keys.KeyNames(keymap.History, "clear"), h.keys.KeyNames(keymap.History, "up"), h.keys.KeyNames(keymap.History, "down"), h.keys.KeyNames(keymap.History, "page_up"), h.keys.KeyNames(keymap.History, "page_down"), common.Reset, display.ClearEnd)
 	t.HideCursor()
 }
 func (h *History) Process(input common.Input) bool {
-	if input.KeyCode != 0 {
-		switch input.KeyCode {
-		case display.CursorUp:
-			if h.offset > 0 {
-				h.offset--
-				h.redraw(true)
-			} else {
-				h.bell()
-			}
-			return false
-
-		case display.CursorDown:
-			if h.offset < h.offsetMax {
-				h.offset++
-				h.redraw(true)
-			} else {
-				h.bell()
-			}
-			return false
-
-		default:
-			fmt.Printf("")
+	switch h.keys.Action(keymap.History, input) {
+	case "up":
+		if h.offset > 0 {
+			h.offset--
+			h.redraw(true)
+		} else {
+			h.bell()
 		}
-	} else {
-		switch input.Ascii {
-		case 's':
-			h.silence = !h.silence
-			if !h.silence {
-				h.bell()
-			}
-			return false
-		case 'q':
-			if h.offset > h.pageSize {
-				h.offset -= h.pageSize
-				h.redraw(true)
-			} else if h.offset > 0 {
-				h.offset = 0
-				h.redraw(true)
-			} else {
-				h.bell()
-			}
-			return false
-
-		case 'a':
-			if h.offset < h.offsetMax -h.pageSize {
-				h.offset += h.pageSize
-				h.redraw(true)
-			} else if h.offset < h.offsetMax {
-				h.offset = h.offsetMax
-				h.redraw(true)
-			} else {
-				h.bell()
-			}
-			return false
-
-		case 'c':
-			h.messages = []string{}
+	case "down":
+		if h.offset < h.offsetMax {
+			h.offset++
 			h.redraw(true)
-			return false
+		} else {
+			h.bell()
+		}
+	case "silence":
+		h.silence = !h.silence
+		if !h.silence {
+			h.bell()
 		}
+	case "page_up":
+		if h.offset > h.pageSize {
+			h.offset -= h.pageSize
+			h.redraw(true)
+		} else if h.offset > 0 {
+			h.offset = 0
+			h.redraw(true)
+		} else {
+			h.bell()
+		}
+	case "page_down":
+		if h.offset < h.offsetMax -h.pageSize {
+			h.offset += h.pageSize
+			h.redraw(true)
+		} else if h.offset < h.offsetMax {
+			h.offset = h.offsetMax
+			h.redraw(true)
+		} else {
+			h.bell()
+		}
+	case "clear":
+		h.messages = []string{}
+		h.redraw(true)
+	default:
+		return true
 	}
-	return true
+	return false
 }
 
 func (h *History) bell() {
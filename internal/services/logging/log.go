@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"sync"
 	"time"
 )
@@ -154,6 +155,7 @@ func (l *Log) Dump() {
 	}
 }
 
-func (l *Log) HistoryViewer() common.UI {
+func (l *Log) HistoryViewer(keys *keymap.Keymap) common.UI {
+	l.history.keys = keys
 	return l.history
 }
\ No newline at end of file
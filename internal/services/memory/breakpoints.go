@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
+	"strings"
+)
+
+// Breakpoint pauses the board when the instruction at Address is fetched and
+// its Condition, if any, evaluates to true. Conditions are expressions over the
+// machine state, e.g. "A == $10 && C", "cycles > 5000" or "hits >= 10".
+type Breakpoint struct {
+	Address   uint16
+	Condition string
+	Label     string
+	Enabled   bool
+	Hits      int
+	condition expression.Node
+}
+
+// breakpointEnv adds the breakpoint's hit count and memory reads to the driver's environment
+type breakpointEnv struct {
+	bp     *Breakpoint
+	env    expression.Env
+	memory *Memory
+}
+
+func (e *breakpointEnv) Lookup(name string) (int64, bool) {
+	if strings.ToLower(name) == "hits" {
+		return int64(e.bp.Hits), true
+	} else if e.env != nil {
+		return e.env.Lookup(name)
+	}
+	return 0, false
+}
+func (e *breakpointEnv) Peek(address uint16) uint8 {
+	return e.memory.peek(address)
+}
+
+func (b *Breakpoint) String() string {
+	str := "$" + display.HexAddress(b.Address)
+	if b.Condition != "" {
+		str = fmt.Sprintf("%s if %s", str, b.Condition)
+	}
+	if b.Label != "" {
+		str = fmt.Sprintf("%s (%s)", str, b.Label)
+	}
+	return str
+}
+
+// conditionNames are the identifiers a condition may use: the breakpoint's hit
+// count, the driver's machine state, the registers and the flags latched in the
+// status byte. B and D aren't latched, so they can't be tested.
+var conditionNames = []string{"hits", "cycles", "pc", "address", "step", "phase", "status", "opcode", "a", "x", "y", "sp", "n", "v", "i", "z", "c"}
+
+// registerNames are the condition names read from the register latches, which
+// need firmware with the 'x' command
+var registerNames = []string{"a", "x", "y", "sp"}
+
+// setCondition compiles a condition, rejecting unknown names, and register names
+// when the registers can't be read
+func (b *Breakpoint) setCondition(condition string, registers bool) error {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		b.Condition, b.condition = "", nil
+		return nil
+	}
+	node, err := expression.Parse(condition)
+	if err != nil {
+		return err
+	}
+	for _, name := range expression.Identifiers(node) {
+		if !isName(name, conditionNames) {
+			return fmt.Errorf("unknown identifier '%s'", name)
+		} else if !registers && isName(name, registerNames) {
+			return fmt.Errorf("register '%s' can't be read: the firmware has no register command", name)
+		}
+	}
+	b.Condition, b.condition = condition, node
+	return nil
+}
+func isName(name string, names []string) bool {
+	for _, known := range names {
+		if strings.EqualFold(name, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetBreakPoint places, or replaces, a breakpoint with an optional condition on an opcode
+func (m *Memory) SetBreakPoint(address uint16, condition string) error {
+	root, found := m.getRootAddress(address)
+	if !found {
+		return fmt.Errorf("no opcode at %s", display.HexAddress(address))
+	}
+	me := m.getEntry(root)
+	bp := &Breakpoint{Address: root, Enabled: true}
+	if old := me.breakpoint; old != nil {
+		bp.Label = old.Label
+	}
+	if err := bp.setCondition(condition, m.registers()); err != nil {
+		return err
+	}
+	me.breakpoint = bp
+	m.log.Infof("Break point set at %s", bp)
+	m.saveBreakPoints()
+	m.redraw(false)
+	return nil
+}
+
+// CheckBreakPoint is called on each instruction fetch. It counts the hit and
+// evaluates the breakpoint's condition, reporting whether the board should pause.
+// A condition that can't be evaluated, such as one testing a register that hasn't
+// been read since a reset, doesn't pause this fetch but is tried again on the next.
+func (m *Memory) CheckBreakPoint(address uint16, env expression.Env) bool {
+	if m.checkTemporary(address) {
+		return true
+	}
+	me, found := m.getRootInstruction(address)
+	if !found || me.breakpoint == nil || !me.breakpoint.Enabled {
+		return false
+	}
+	bp := me.breakpoint
+	if bp.condition == nil && bp.Condition != "" {
+		// A saved condition that failed to compile never pauses the board
+		return false
+	}
+	bp.Hits++
+	if bp.condition == nil {
+		return true
+	}
+	result, err := expression.IsTrue(bp.condition, &breakpointEnv{bp: bp, env: env, memory: m})
+	if err != nil {
+		m.log.Debugf("Break point %s not checked: %v", bp, err)
+		return false
+	}
+	return result
+}
+
+// Temporary breakpoints back the step and run-to commands. They pause the board
+// the same way as ordinary breakpoints, are never saved, and are all removed as
+// soon as any one of them is hit.
+
+// BreakOnNextFetch pauses on the next instruction fetched
+func (m *Memory) BreakOnNextFetch() {
+	m.ClearTemporaryBreakPoints()
+	m.pauseNext = true
+}
+
+// RunTo places a temporary breakpoint on the opcode at, or containing, an address
+func (m *Memory) RunTo(address uint16) bool {
+	root, found := m.getRootAddress(address)
+	if !found {
+		m.log.Warnf("No opcode at %s", display.HexAddress(address))
+		return false
+	}
+	m.ClearTemporaryBreakPoints()
+	m.temporary[root] = true
+	m.log.Infof("Running to %s", display.HexAddress(root))
+	return true
+}
+
+// StepOver runs a subroutine call to completion by breaking at the return
+// address. Any other instruction is simply stepped.
+func (m *Memory) StepOver(address uint16) {
+	if m.opCodes.Lookup(m.peek(address)).Name == "JSR" {
+		m.ClearTemporaryBreakPoints()
+		m.temporary[address + 3] = true
+		m.log.Infof("Stepping over JSR at %s", display.HexAddress(address))
+		return
+	}
+	m.BreakOnNextFetch()
+}
+
+// StepOut runs until the RTS that matches the current subroutine level, then
+// pauses on the instruction it returns to
+func (m *Memory) StepOut() {
+	m.ClearTemporaryBreakPoints()
+	m.stepOut = 1
+	m.log.Info("Stepping out of subroutine")
+}
+func (m *Memory) ClearTemporaryBreakPoints() {
+	m.temporary = map[uint16]bool{}
+	m.pauseNext = false
+	m.stepOut = 0
+}
+func (m *Memory) checkTemporary(address uint16) bool {
+	if m.pauseNext || m.temporary[address] {
+		m.ClearTemporaryBreakPoints()
+		return true
+	}
+	if m.stepOut > 0 {
+		switch m.opCodes.Lookup(m.peek(address)).Name {
+		case "JSR":
+			m.stepOut++
+		case "RTS":
+			if m.stepOut--; m.stepOut == 0 {
+				m.pauseNext = true
+			}
+		}
+	}
+	return false
+}
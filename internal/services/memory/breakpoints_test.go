@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestSetCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		registers bool
+		ok        bool
+	}{
+		{"", false, true},
+		{"hits >= 10", false, true},
+		{"pc == $0200 && C", false, true},
+		{"A == $10", true, true},
+		{"A == $10", false, false},
+		{"sp < $F0 or not z", false, false},
+		{"B", true, false},
+		{"cursor == 0", true, false},
+		{"1 +", true, false},
+	}
+	for _, test := range tests {
+		bp := &Breakpoint{}
+		err := bp.setCondition(test.condition, test.registers)
+		if (err == nil) != test.ok {
+			t.Errorf("setCondition(%q, registers %v) = %v, want ok %v", test.condition, test.registers, err, test.ok)
+		} else if err == nil && bp.Condition != test.condition {
+			t.Errorf("setCondition(%q) kept %q", test.condition, bp.Condition)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"strings"
+)
+
+// BreakPointManager is a page listing every break and watch point, allowing
+// them to be enabled, disabled, deleted, labelled or jumped to.
+type BreakPointManager struct {
+	memory   *Memory
+	list     common.List
+	editing  bool
+	input    string
+}
+
+type managerItem struct {
+	bp *Breakpoint
+	wp *Watchpoint
+}
+
+func (m *Memory) BreakPointViewer() common.UI {
+	return &BreakPointManager{memory: m, list: common.List{Select: true}}
+}
+
+func (b *BreakPointManager) items() []managerItem {
+	var items []managerItem
+	for _, bp := range b.memory.BreakPoints() {
+		items = append(items, managerItem{bp: bp})
+	}
+	for _, wp := range b.memory.Watchpoints() {
+		items = append(items, managerItem{wp: wp})
+	}
+	return items
+}
+
+func (b *BreakPointManager) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	t.HideCursor()
+	items := b.items()
+	b.list.Layout(t.Rows() - 4, len(items))
+
+	t.PrintAtf(1, 1, "%sBreak and watch points%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	t.PrintAtf(1, 2, "%s   On  Type   Where                 Hits  Condition / Label%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	for row := 0; row < b.list.Rows; row++ {
+		line := ""
+		if i, ok := b.list.Item(row); ok {
+			line = b.describe(items[i])
+			if i == b.list.Cursor {
+				line = common.Reversed + line + common.Reset
+			}
+		}
+		t.PrintAtf(1, row+3, "%s%s", line, display.ClearEnd)
+	}
+	if len(items) == 0 {
+		t.PrintAtf(4, 3, "%sNo break or watch points set%s", common.Grey, common.Reset)
+	}
+
+	if b.editing {
+		t.PrintAtf(1, t.Rows(), "%sLabel: %s%s%s", common.Yellow, common.White, b.input, display.ClearEnd)
+		t.ShowCursor()
+	} else {
+		common.Footer(t, b.memory.keys.Help(keymap.Breakpoints, "toggle", "delete", "label", "goto") + ", any other key to exit")
+	}
+}
+func (b *BreakPointManager) describe(item managerItem) string {
+	enabled, kind, where, hits, detail := false, "", "", 0, ""
+	if item.bp != nil {
+		enabled, kind, hits = item.bp.Enabled, "break", item.bp.Hits
+		where = fmt.Sprintf("$%s %s", display.HexAddress(item.bp.Address), b.memory.opCodes.Lookup(b.memory.peek(item.bp.Address)).Name)
+		if item.bp.Condition != "" {
+			detail = "if " + item.bp.Condition
+		}
+		if item.bp.Label != "" {
+			detail = strings.TrimSpace(detail + " [" + item.bp.Label + "]")
+		}
+	} else {
+		enabled, kind, hits = item.wp.Enabled, "watch", item.wp.Hits
+		where = item.wp.rangeString() + " " + item.wp.access()
+		if item.wp.HasValue {
+			detail = "=$" + display.HexData(item.wp.Value)
+		}
+		if item.wp.Label != "" {
+			detail = strings.TrimSpace(detail + " [" + item.wp.Label + "]")
+		}
+	}
+	state := fmt.Sprintf("%s[ ]", common.Grey)
+	if enabled {
+		state = fmt.Sprintf("%s[x]", common.BrightGreen)
+	}
+	return fmt.Sprintf("   %s %s%-6s %-21s %4d  %s%s", state, common.White, kind, where, hits, detail, common.Reset)
+}
+
+func (b *BreakPointManager) Process(input common.Input) bool {
+	items := b.items()
+	if b.editing {
+		switch input.Ascii {
+		case 27:
+			b.editing = false
+		case 127:
+			if len(b.input) > 0 {
+				b.input = b.input[:len(b.input)-1]
+			}
+		case 13:
+			b.editing = false
+			if b.list.Cursor < len(items) {
+				b.setLabel(items[b.list.Cursor], strings.TrimSpace(b.input))
+			}
+		default:
+			if input.Ascii >= 32 && input.Ascii < 127 {
+				b.input += string(rune(input.Ascii))
+			}
+		}
+		b.memory.redraw(false)
+		return false
+	}
+
+	action := b.memory.keys.Action(keymap.Breakpoints, input)
+	if b.list.Move(action) {
+		b.memory.redraw(false)
+		return false
+	} else if b.list.Cursor >= len(items) {
+		return true
+	}
+	item := items[b.list.Cursor]
+	switch action {
+	case "toggle":
+		if item.bp != nil {
+			item.bp.Enabled = !item.bp.Enabled
+		} else {
+			item.wp.Enabled = !item.wp.Enabled
+		}
+		b.memory.saveBreakPoints()
+	case "delete":
+		if item.bp != nil {
+			b.memory.RemoveBreakPoint(item.bp.Address)
+		} else {
+			b.memory.RemoveWatchpoint(item.wp)
+		}
+	case "label":
+		b.editing = true
+		b.input = ""
+		if item.bp != nil {
+			b.input = item.bp.Label
+		} else {
+			b.input = item.wp.Label
+		}
+	case "goto":
+		if item.bp != nil {
+			b.memory.Goto(item.bp.Address)
+		} else {
+			b.memory.Goto(item.wp.Start)
+		}
+		return true
+	default:
+		return true
+	}
+	b.memory.redraw(true)
+	return false
+}
+func (b *BreakPointManager) setLabel(item managerItem, label string) {
+	if item.bp != nil {
+		item.bp.Label = label
+	} else {
+		item.wp.Label = label
+	}
+	b.memory.saveBreakPoints()
+}
@@ -6,22 +6,43 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/undo"
+	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
 )
 
 const (
-	read      = common.BrightYellow
-	written   = common.BrightRed
-	normal    = common.Blue
-	current   = common.BrightGreen
-	lineCount = 11
+	read       = common.BrightYellow
+	written    = common.BrightRed
+	normal     = common.Blue
+	alternate  = common.Cyan
+	current    = common.BrightGreen
+	lineCount  = 11
+	blockWidth = 53
 )
 
+const (
+	promptNone = iota
+	promptGoto
+	promptSearch
+	promptFill
+	promptCopy
+	promptWatch
+	promptCondition
+)
+
+var prompts = map[string]int{"goto": promptGoto, "search": promptSearch, "fill": promptFill, "copy": promptCopy, "watch": promptWatch, "break_if": promptCondition}
+// Numbers typed at the prompts are hex unless prefixed with #, except in break conditions
+var promptLabels = []string{"", "Goto (hex): ", "Search (hex bytes or \"text\"): ", "Fill start,end,value (hex): ", "Copy start,end,dest (hex): ", "Watch start[..end] [r|w|rw] [=value] (hex): ", "Break if: "}
+
 var colorSet = [][]interface{}{
 		{common.BrightMagenta, common.BrightYellow, common.BrightMagenta, "", "", "", "", common.Grey, common.Reset},
 		{common.BrightMagenta, "", "", common.BrightYellow, common.BrightMagenta, "", "", common.Grey, common.Reset},
@@ -36,7 +57,7 @@ type disassemblyEntry struct {
 type memoryEntry struct {
 	data             byte
 	opCode           bool
-	breakpoint       bool
+	breakpoint       *Breakpoint
 	disassembleIndex uint16
 	void             bool
 }
@@ -56,12 +77,28 @@ type Memory struct {
 	redraw         func(bool)
 	inputMode      bool
 	input          string
-	lastInput      byte
-	lastAddress    uint16
-	hasLastInput   bool
+	undo           *undo.Stack
 	bpfilename     string
+	busAddress     uint16
+	follow         bool
+	ascii          bool
+	prompt         int
+	promptInput    string
+	lastSearch     []byte
+	watchpoints    []*Watchpoint
+	watchHit       string
+	temporary      map[uint16]bool
+	pauseNext      bool
+	stepOut        int
+	keys           *keymap.Keymap
+	registers      func() bool
+	machine        func(name string) (int64, bool)
 }
-func New(log *logging.Log, opCodes *instructionSet.OpCodes, terminal *display.Terminal, redraw func(bool)) *Memory {
+
+// New creates the memory editor. registers reports whether the board's register
+// latches can be read, which break conditions on A, X, Y and SP need. machine
+// resolves the machine state, such as pc and a, in address expressions.
+func New(log *logging.Log, opCodes *instructionSet.OpCodes, terminal *display.Terminal, redraw func(bool), undo *undo.Stack, keys *keymap.Keymap, registers func() bool, machine func(name string) (int64, bool)) *Memory {
 	return &Memory{
 		lastAction:  normal,
 		opCodes:     opCodes,
@@ -72,6 +109,12 @@ func New(log *logging.Log, opCodes *instructionSet.OpCodes, terminal *display.Te
 		cursor:      common.Coord{X:0, Y:0},
 		input:       "xx",
 		redraw:      redraw,
+		follow:      true,
+		undo:        undo,
+		temporary:   map[uint16]bool{},
+		keys:        keys,
+		registers:   registers,
+		machine:     machine,
 	}
 }
 
@@ -231,9 +274,10 @@ func (m *Memory) ReadMemory(address uint16) (byte, bool) {
 func (m *Memory) WriteMemory(address uint16, data byte) bool {
 	me := m.getEntry(address)
 	//if !me.opCode {
-		me.data = data
+		me.data, me.void = data, false
 		m.lastAction = written
 		m.log.Infof("Memory[%s] set to %s", display.HexAddress(address), display.HexData(me.data))
+		m.checkWatchpoints(address, data, true)
 		return true
 	//} else {
 	//	m.log.Errorf("Memory[%s] represents an opCode and cannot be changed", display.HexAddress(address))
@@ -246,50 +290,68 @@ func (m *Memory) ToggleBreakPoint(address uint16) {
 	} else if !me.opCode {
 		m.log.Info("Selected value is data, not an opcode")
 	} else {
-		me.breakpoint = !me.breakpoint
+		if me.breakpoint == nil {
+			root, _ := m.getRootAddress(address)
+			me.breakpoint = &Breakpoint{Address: root, Enabled: true}
+		} else {
+			me.breakpoint = nil
+		}
+		m.saveBreakPoints()
 		m.redraw(false)
 	}
 }
 func (m *Memory) HasBreakPoint(address uint16) bool {
 	if me, found := m.getRootInstruction(address); found {
-		return me.breakpoint
+		return me.breakpoint != nil && me.breakpoint.Enabled
 	} else {
 		return false
 	}
 }
 func (m * Memory) getRootInstruction(address uint16) (*memoryEntry, bool) {
-	maxLoops := 3
-	var me *memoryEntry
-	for me == nil || !me.opCode {
-		me = m.getEntry(address)
+	if root, found := m.getRootAddress(address); found {
+		return m.getEntry(root), true
+	}
+	return nil, false
+}
+func (m *Memory) getRootAddress(address uint16) (uint16, bool) {
+	for maxLoops := 3; maxLoops > 0; maxLoops-- {
+		me := m.getEntry(address)
 		if me.void {
-			return nil, false
+			return 0, false
+		} else if me.opCode {
+			return address, true
 		}
 		address--
-		maxLoops--
-		if maxLoops == 0 {
-			return nil, false
-		}
 	}
-	return me, true
+	return 0, false
 }
 
 func (m *Memory) MemoryBlock(address uint16) (lines []string) {
-	// Round down to nearest block
+	// Round down to nearest block, unless the user has navigated away from the bus address
+	m.busAddress = address
 	start := address - address % 256
-	if m.displayAddress != start {
-		m.hasLastInput = false
-		m.displayAddress = start
+	if !m.follow {
+		start = m.displayAddress
+	}
+	m.displayAddress = start
+	if m.ascii {
+		lines = append(lines, common.Yellow+ "     0 1 2 3 4 5 6 7 8 9 A B C D E F 0123456789ABCDEF" +common.Reset)
+	} else {
+		lines = append(lines, common.Yellow+ "     0  1  2  3  4  5  6  7   8  9  A  B  C  D  E  F" +common.Reset)
 	}
-	lines = append(lines, common.Yellow+ "     0  1  2  3  4  5  6  7   8  9  A  B  C  D  E  F" +common.Reset)
 
 	colour, lastColour, line := normal, "", ""
 	var second = 0
 	for i := 0; i < 16; i++ {
 		line = fmt.Sprintf("%s%s%s%s ", common.Yellow, display.HEX[start >> 12], display.HEX[start >> 8 & 15], display.HEX[start >> 4 & 15])
+		text := ""
 		for j := 0; j < 16; j++ {
 			me := m.getEntry(start)
 			colour = normal
+			if m.ascii && j % 2 == 1 {
+				// The ASCII view packs the bytes together, so alternate bytes are told apart by colour
+				colour = alternate
+			}
 			if address == start {
 				colour = m.lastAction
 			}
@@ -300,9 +362,19 @@ func (m *Memory) MemoryBlock(address uint16) (lines []string) {
 				colour = common.BrightRed
 				value = (m.input + "__")[:2]
 			} else {
-				if me.breakpoint {
-					lastColour = lastColour + common.BGRed
-					value = common.BGRed + value + common.Reset
+				if m.isWatched(start) {
+					lastColour = lastColour + common.BGMagenta
+					value = common.BGMagenta + value + common.Reset
+				}
+				if me.breakpoint != nil {
+					bg := common.BGRed
+					if !me.breakpoint.Enabled {
+						bg = common.BGGrey
+					} else if me.breakpoint.condition != nil {
+						bg = common.BGYellow
+					}
+					lastColour = lastColour + bg
+					value = bg + value + common.Reset
 				}
 				if me.opCode && !me.void {
 					lastColour = lastColour + common.Underline
@@ -310,22 +382,55 @@ func (m *Memory) MemoryBlock(address uint16) (lines []string) {
 				}
 			}
 
-			line += fmt.Sprintf("%s%s ", colour, value)
-			if j == 7 {
-				line += " "
+			if m.ascii {
+				line += colour + value
+				text += asciiChar(me.data, m.cursor.X == j && m.cursor.Y == i)
+			} else {
+				line += fmt.Sprintf("%s%s ", colour, value)
+				if j == 7 {
+					line += " "
+				}
 			}
 			start++
 		}
 		lastColour = ""
+		if m.ascii {
+			line += common.Reset + " " + text
+		}
 		lines = append(lines, fmt.Sprintf("%s%s", line, common.Reset))
 		if i == 7 {
 			second++
 			lines = append(lines, "")
 		}
 	}
+	lines = append(lines, m.promptLine())
 	m.lastAction = current
 	return lines
 }
+
+// asciiChar shows a byte in the ASCII view, printable or as a dot, reversed under the cursor
+func asciiChar(data byte, cursor bool) string {
+	c := "."
+	if data >= 0x20 && data < 0x7F {
+		c = string(rune(data))
+	}
+	if cursor {
+		return common.Reversed + normal + c + common.Reset
+	}
+	return normal + c
+}
+func (m *Memory) promptLine() string {
+	text := ""
+	if m.prompt != promptNone {
+		text = promptLabels[m.prompt] + m.promptInput
+	} else if !m.follow {
+		text = fmt.Sprintf("Page %s (press . to follow the bus)", display.HexAddress(m.displayAddress))
+	}
+	if len(text) > blockWidth {
+		text = text[len(text)-blockWidth:]
+	}
+	return fmt.Sprintf("%s%-*s%s", common.Yellow, blockWidth, text, common.Reset)
+}
 func (m *Memory) InstructionBlock(instrAddr, address uint16) []string {
 
 	me := m.getEntry(instrAddr)
@@ -351,8 +456,14 @@ func (m *Memory) InstructionBlock(instrAddr, address uint16) []string {
 			} else {
 				line = fmt.Sprintf(line, colorSet[colorSetIndex]...)
 			}
-			if le.breakpoint {
-				line = common.BGRed + line
+			if le.breakpoint != nil {
+				if !le.breakpoint.Enabled {
+					line = common.BGGrey + line
+				} else if le.breakpoint.condition != nil {
+					line = common.BGYellow + line
+				} else {
+					line = common.BGRed + line
+				}
 			}
 			lines = append(lines, line)
 		}
@@ -397,6 +508,19 @@ func (m *Memory) Right(n int) {
 	}
 }
 func (m *Memory) PositionCursor() {
+	if m.prompt != promptNone {
+		col := len(promptLabels[m.prompt]) + len(m.promptInput) + 1
+		if col > blockWidth {
+			col = blockWidth
+		}
+		// The prompt line follows the last row of bytes
+		m.terminal.At(col, m.yOffset[1] + 16)
+		return
+	}
+	if m.ascii {
+		m.terminal.At(m.cursor.X * 2 + m.xOffset[0] +len(m.input), m.cursor.Y + m.yOffset[(m.cursor.Y)/8])
+		return
+	}
 	m.terminal.At(m.cursor.X * 3 + m.xOffset[(m.cursor.X)/8] +len(m.input), m.cursor.Y + m.yOffset[(m.cursor.Y)/8])
 }
 func (m *Memory) CursorPosition() string {
@@ -405,61 +529,62 @@ func (m *Memory) CursorPosition() string {
 	return display.HexAddress(address) + "->" + m.opCodes.Lookup(me.data).Name
 }
 func (m *Memory) KeyIntercept(input common.Input) bool {
-	if input.KeyCode != 0 && !m.inputMode {
-		switch input.KeyCode {
-		case display.CursorUp:
-			m.Up(1)
-		case display.CursorDown:
-			m.Down(1)
-		case display.CursorLeft:
-			m.Left(1)
-		case display.CursorRight:
-			m.Right(1)
-		default:
-			// keycode not processed
-			return false
+	if m.prompt != promptNone {
+		return m.promptIntercept(input)
+	}
+	if m.inputMode && input.KeyCode == 0 && isHex(input.Ascii) {
+		m.input += strings.ToUpper(string(rune(input.Ascii)))
+		if len(m.input) == 2 {
+			bs, _ := hex.DecodeString(m.input)
+			address := m.cursorAddress()
+			m.inputMode = false
+			m.writeBytes(address, bs, fmt.Sprintf("Memory[%s] %s -> %s", display.HexAddress(address), display.HexData(m.peek(address)), display.HexData(bs[0])))
 		}
+		m.redraw(true)
 		return true
-	} else  {
-		switch input.Ascii {
-		case '0','1','2','3','4','5','6','7','8','9','a','b','c','d','e','f','A','B','C','D','E','F':
-			if m.inputMode {
-				m.input += strings.ToUpper(string(input.Ascii))
-				if len(m.input) == 2 {
-					bs, _ := hex.DecodeString(m.input)
-					m.lastAddress = m.displayAddress + uint16(m.cursor.X) + uint16(m.cursor.Y*16)
-					me := m.getEntry(m.lastAddress)
-					m.lastInput = me.data
-					me.data = bs[0]
-					m.inputMode = false
-					m.hasLastInput = true
-					m.disassembly = m.disassemble()
-				}
-				m.redraw(true)
-			} else if input.Ascii == 'b' {
-				m.ToggleBreakPoint(m.displayAddress + uint16(m.cursor.X) + uint16(m.cursor.Y*16))
-				m.saveBreakPoints()
-			} else {
-				return false
-			}
+	}
 
-		case 13, 127:
-			if !m.inputMode {
-				m.input = ""
-				m.inputMode = true
-				m.redraw(false)
-			}
-		case 26:
-			if m.hasLastInput {
-				m.getEntry(m.lastAddress).data = m.lastInput
-				m.disassembly = m.disassemble()
-				m.hasLastInput = false
-				m.redraw(false)
-			}
-		case 27:
-			m.inputMode = false
+	switch action := m.keys.Action(keymap.Memory, input); action {
+	case "cancel":
+		m.inputMode = false
+		m.input = ""
+		m.redraw(false)
+	case "edit":
+		if !m.inputMode {
 			m.input = ""
+			m.inputMode = true
+			m.redraw(false)
+		}
+	default:
+		// Other keys are only taken while not editing a byte
+		if m.inputMode {
+			return false
+		}
+		switch action {
+		case "up":
+			m.Up(1)
+		case "down":
+			m.Down(1)
+		case "left":
+			m.Left(1)
+		case "right":
+			m.Right(1)
+		case "page_up":
+			m.Page(-1)
+		case "page_down":
+			m.Page(1)
+		case "toggle_breakpoint":
+			m.ToggleBreakPoint(m.cursorAddress())
+		case "goto", "search", "fill", "copy", "watch", "break_if":
+			m.promptInput = ""
+			m.prompt = prompts[action]
 			m.redraw(false)
+		case "follow":
+			m.follow = true
+			m.redraw(false)
+		case "ascii":
+			m.ascii = !m.ascii
+			m.redraw(true)
 		default:
 			// key not processed
 			return false
@@ -468,37 +593,400 @@ func (m *Memory) KeyIntercept(input common.Input) bool {
 	// Key processed
 	return true
 }
+func isHex(ascii int) bool {
+	return ascii >= '0' && ascii <= '9' || ascii >= 'a' && ascii <= 'f' || ascii >= 'A' && ascii <= 'F'
+}
+func (m *Memory) promptIntercept(input common.Input) bool {
+	if input.KeyCode != 0 {
+		return true
+	}
+	switch input.Ascii {
+	case 27:
+		m.prompt = promptNone
+	case 127:
+		if len(m.promptInput) > 0 {
+			m.promptInput = m.promptInput[:len(m.promptInput)-1]
+		}
+	case 13:
+		prompt := m.prompt
+		m.prompt = promptNone
+		m.runPrompt(prompt, strings.TrimSpace(m.promptInput))
+	default:
+		if input.Ascii >= 32 && input.Ascii < 127 {
+			m.promptInput += string(rune(input.Ascii))
+		}
+	}
+	m.redraw(false)
+	return true
+}
+func (m *Memory) runPrompt(prompt int, text string) {
+	switch prompt {
+	case promptGoto:
+		if address, err := expression.EvalAddressHex(text, m); err != nil {
+			m.log.Warnf("Goto failed: %v", err)
+		} else {
+			m.Goto(address)
+		}
+	case promptSearch:
+		if pattern, err := m.parsePattern(text); err != nil {
+			m.log.Warnf("Search failed: %v", err)
+		} else {
+			m.Search(pattern)
+		}
+	case promptFill:
+		if args, err := m.parseArgs(text, 3); err != nil {
+			m.log.Warnf("Fill failed: %v", err)
+		} else if args[2] > 0xFF {
+			m.log.Warnf("Fill value out of range: %d", args[2])
+		} else {
+			m.Fill(args[0], args[1], uint8(args[2]))
+		}
+	case promptCopy:
+		if args, err := m.parseArgs(text, 3); err != nil {
+			m.log.Warnf("Copy failed: %v", err)
+		} else {
+			m.Copy(args[0], args[1], args[2])
+		}
+	case promptWatch:
+		if text == "" {
+			m.ToggleWatchpoint(m.cursorAddress())
+		} else if wp, err := ParseWatchpoint(text, m); err != nil {
+			m.log.Warnf("Watchpoint failed: %v", err)
+		} else {
+			m.AddWatchpoint(wp)
+		}
+	case promptCondition:
+		if err := m.SetBreakPoint(m.cursorAddress(), text); err != nil {
+			m.log.Warnf("Break point failed: %v", err)
+		}
+	}
+}
+
+// Lookup resolves identifiers used in address expressions, whether typed at a
+// memory editor prompt or on the command line: the editor's cursor, bus and page,
+// then the machine state
+func (m *Memory) Lookup(name string) (int64, bool) {
+	switch strings.ToLower(name) {
+	case "cursor":
+		return int64(m.cursorAddress()), true
+	case "bus":
+		return int64(m.busAddress), true
+	case "page":
+		return int64(m.displayAddress), true
+	}
+	if m.machine != nil {
+		return m.machine(name)
+	}
+	return 0, false
+}
+func (m *Memory) parseArgs(text string, count int) ([]uint16, error) {
+	fields := strings.Split(text, ",")
+	if len(fields) != count {
+		fields = strings.Fields(text)
+	}
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d values, found %d", count, len(fields))
+	}
+	args := make([]uint16, count)
+	for i, field := range fields {
+		value, err := expression.EvalAddressHex(field, m)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+func (m *Memory) parsePattern(text string) ([]byte, error) {
+	if text == "" {
+		if len(m.lastSearch) == 0 {
+			return nil, fmt.Errorf("no previous search")
+		}
+		return m.lastSearch, nil
+	}
+	if strings.HasPrefix(text, "\"") {
+		text = strings.TrimSuffix(text[1:], "\"")
+		if text == "" {
+			return nil, fmt.Errorf("empty search text")
+		}
+		return []byte(text), nil
+	}
+	var pattern []byte
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		value, err := expression.EvalHex(field, m)
+		if err != nil {
+			return nil, err
+		} else if value < 0 || value > 0xFF {
+			return nil, fmt.Errorf("byte out of range: %s", field)
+		}
+		pattern = append(pattern, uint8(value))
+	}
+	return pattern, nil
+}
+
+// CursorAddress is the address under the memory editor's cursor
+func (m *Memory) CursorAddress() uint16 {
+	return m.cursorAddress()
+}
+func (m *Memory) cursorAddress() uint16 {
+	return m.displayAddress + uint16(m.cursor.X) + uint16(m.cursor.Y*16)
+}
+func (m *Memory) Peek(address uint16) uint8 {
+	return m.peek(address)
+}
+func (m *Memory) peek(address uint16) byte {
+	if me := m.memory[address]; me != nil {
+		return me.data
+	}
+	return 0
+}
+
+// mapped gives the byte at an address, and false for an address that has been
+// neither loaded from the ROM nor written
+func (m *Memory) mapped(address uint16) (byte, bool) {
+	if me := m.memory[address]; me != nil && !me.void {
+		return me.data, true
+	}
+	return 0, false
+}
+func (m *Memory) Goto(address uint16) {
+	m.follow = false
+	m.displayAddress = address &^ 0xFF
+	m.cursor.X = int(address & 0x0F)
+	m.cursor.Y = int(address >> 4 & 0x0F)
+	m.redraw(false)
+}
+func (m *Memory) Page(n int) {
+	m.follow = false
+	m.displayAddress = uint16(int(m.displayAddress) + n*256)
+	m.redraw(false)
+}
+func (m *Memory) Search(pattern []byte) {
+	m.lastSearch = pattern
+	start := m.cursorAddress() + 1
+	for i := 0; i < 65536; i++ {
+		address := start + uint16(i)
+		found := true
+		for j, b := range pattern {
+			// Unmapped memory never matches, so a search can't find zeros in a hole or span one
+			if data, ok := m.mapped(address+uint16(j)); !ok || data != b {
+				found = false
+				break
+			}
+		}
+		if found {
+			m.log.Infof("Found %d byte(s) at %s", len(pattern), display.HexAddress(address))
+			m.Goto(address)
+			return
+		}
+	}
+	m.log.Warn("Search pattern not found")
+}
+func (m *Memory) Fill(start, end uint16, value uint8) {
+	if end < start {
+		m.log.Warnf("Fill end %s is before start %s", display.HexAddress(end), display.HexAddress(start))
+		return
+	}
+	bs := make([]byte, int(end)-int(start)+1)
+	for i := range bs {
+		bs[i] = value
+	}
+	m.writeBytes(start, bs, fmt.Sprintf("fill of Memory[%s-%s] with %s", display.HexAddress(start), display.HexAddress(end), display.HexData(value)))
+	m.log.Infof("Memory[%s-%s] filled with %s", display.HexAddress(start), display.HexAddress(end), display.HexData(value))
+}
+func (m *Memory) Copy(start, end, dest uint16) {
+	if end < start {
+		m.log.Warnf("Copy end %s is before start %s", display.HexAddress(end), display.HexAddress(start))
+		return
+	}
+	bs := make([]byte, 0, int(end)-int(start)+1)
+	for address := int(start); address <= int(end); address++ {
+		bs = append(bs, m.peek(uint16(address)))
+	}
+	m.writeBytes(dest, bs, fmt.Sprintf("copy of Memory[%s-%s] to %s", display.HexAddress(start), display.HexAddress(end), display.HexAddress(dest)))
+	m.log.Infof("Memory[%s-%s] copied to %s", display.HexAddress(start), display.HexAddress(end), display.HexAddress(dest))
+}
+
+// writeBytes stores an edit made from the editor, recording it so that it can be undone
+func (m *Memory) writeBytes(start uint16, bs []byte, description string) {
+	before := make([]byte, len(bs))
+	for i := range bs {
+		before[i] = m.peek(start + uint16(i))
+	}
+	after := append([]byte(nil), bs...)
+	m.setBytes(start, after)
+	m.undo.Push(undo.Action{
+		Description: description,
+		Undo:        func() { m.setBytes(start, before) },
+		Redo:        func() { m.setBytes(start, after) },
+	})
+}
+func (m *Memory) setBytes(start uint16, bs []byte) {
+	for i, b := range bs {
+		me := m.getEntry(start + uint16(i))
+		me.data, me.void = b, false
+	}
+	m.disassembly = m.disassemble()
+	m.redraw(true)
+}
+
+// Break and watch points are kept in a YAML file next to the ROM, e.g. roms/test.bp.yaml:
+//
+//   breakpoints:
+//     - address: $0210
+//       condition: A == 0 && hits > 2
+//       label: main loop
+//       enabled: true
+//   watchpoints:
+//     - range: $0200..$02FF
+//       access: w
+//       value: $00
+//       enabled: true
+//
+// The binary ".<rom>.bp" file used previously is still read when no YAML file exists.
+type bpFile struct {
+	BreakPoints []bpFileEntry `yaml:"breakpoints,omitempty"`
+	WatchPoints []wpFileEntry `yaml:"watchpoints,omitempty"`
+}
+type bpFileEntry struct {
+	Address   string `yaml:"address"`
+	Condition string `yaml:"condition,omitempty"`
+	Label     string `yaml:"label,omitempty"`
+	Enabled   bool   `yaml:"enabled"`
+}
+type wpFileEntry struct {
+	Range   string `yaml:"range"`
+	Access  string `yaml:"access"`
+	Value   string `yaml:"value,omitempty"`
+	Label   string `yaml:"label,omitempty"`
+	Enabled bool   `yaml:"enabled"`
+}
+
 func (m* Memory) makeBPFile() string {
+	dir, filename := path.Split(m.filename)
+	return path.Join(dir, fmt.Sprintf("%s.bp.yaml", filename[:len(filename)-len(filepath.Ext(filename))]))
+}
+func (m* Memory) makeLegacyBPFile() string {
 	dir, filename := path.Split(m.filename)
 	return path.Join(dir, fmt.Sprintf(".%s.bp", filename[:len(filename)-len(filepath.Ext(filename))]))
 }
 func (m *Memory) loadBreakPoints() {
-	if bs, err := ioutil.ReadFile(m.bpfilename); err == nil {
-		for i := uint16(0); i < uint16(len(bs)); i += 2 {
+	m.watchpoints = nil
+	bs, err := ioutil.ReadFile(m.bpfilename)
+	if os.IsNotExist(err) {
+		m.loadLegacyBreakPoints()
+		return
+	} else if err != nil {
+		m.log.Warnf("Failed to read break point file: %v", err)
+		return
+	}
+
+	var file bpFile
+	if err := yaml.Unmarshal(bs, &file); err != nil {
+		m.log.Warnf("Failed to parse break point file %s: %v", m.bpfilename, err)
+		return
+	}
+	for _, entry := range file.BreakPoints {
+		address, err := expression.EvalAddress(entry.Address, nil)
+		if err != nil {
+			m.log.Warnf("Ignoring break point '%s': %v", entry.Address, err)
+			continue
+		}
+		me := m.getEntry(address)
+		if !me.opCode {
+			m.log.Warnf("Ignoring break point at %s: not an opcode", display.HexAddress(address))
+			continue
+		}
+		bp := &Breakpoint{Address: address, Label: entry.Label, Enabled: entry.Enabled}
+		// The firmware isn't known when the ROM is loaded, so register names are allowed here
+		if err := bp.setCondition(entry.Condition, true); err != nil {
+			// Keep the condition so it is saved again, but don't let it break unconditionally
+			bp.Condition, bp.Enabled = entry.Condition, false
+			m.log.Warnf("Disabling break point %s: %v", display.HexAddress(address), err)
+		}
+		me.breakpoint = bp
+	}
+	for _, entry := range file.WatchPoints {
+		spec := strings.TrimSpace(entry.Range + " " + entry.Access)
+		if entry.Value != "" {
+			spec += " =" + entry.Value
+		}
+		if wp, err := ParseWatchpoint(spec, nil); err != nil {
+			m.log.Warnf("Ignoring watchpoint '%s': %v", entry.Range, err)
+		} else {
+			wp.Label, wp.Enabled = entry.Label, entry.Enabled
+			m.watchpoints = append(m.watchpoints, wp)
+		}
+	}
+}
+func (m *Memory) loadLegacyBreakPoints() {
+	if bs, err := ioutil.ReadFile(m.makeLegacyBPFile()); err == nil {
+		for i := uint16(0); i+1 < uint16(len(bs)); i += 2 {
 			bp := binary.LittleEndian.Uint16(bs[i : i+2])
 			entry := m.getEntry(bp)
 			if entry.opCode {
-				entry.breakpoint = true
+				entry.breakpoint = &Breakpoint{Address: bp, Enabled: true}
 			}
 		}
 	}
 }
 func (m *Memory) saveBreakPoints() {
-	bs := make([]byte, 0, len(m.disassembly) * 2)
-	for _, de := range m.disassembly {
-		me := m.getEntry(de.address)
-		if me.void {
-			break
-		} else  if me.breakpoint {
-			bs = bs[:len(bs) + 2]
-			binary.LittleEndian.PutUint16(bs[len(bs) - 2:], de.address)
+	var file bpFile
+	for _, bp := range m.BreakPoints() {
+		file.BreakPoints = append(file.BreakPoints, bpFileEntry{
+			Address:   "$" + display.HexAddress(bp.Address),
+			Condition: bp.Condition,
+			Label:     bp.Label,
+			Enabled:   bp.Enabled,
+		})
+	}
+	for _, wp := range m.watchpoints {
+		entry := wpFileEntry{Range: wp.rangeString(), Access: wp.access(), Label: wp.Label, Enabled: wp.Enabled}
+		if wp.HasValue {
+			entry.Value = "$" + display.HexData(wp.Value)
 		}
+		file.WatchPoints = append(file.WatchPoints, entry)
 	}
-	if len(bs) > 0 {
-		if err := ioutil.WriteFile(m.bpfilename, bs, 0644); err != nil {
-			m.log.Warnf("Failed to write break point file: %v", err)
-		} else {
-			m.log.Info("Break point captured")
+
+	if _, err := os.Stat(m.bpfilename); os.IsNotExist(err) && len(file.BreakPoints) == 0 && len(file.WatchPoints) == 0 {
+		return
+	}
+	if bs, err := yaml.Marshal(&file); err != nil {
+		m.log.Warnf("Failed to encode break points: %v", err)
+	} else if err := ioutil.WriteFile(m.bpfilename, bs, 0644); err != nil {
+		m.log.Warnf("Failed to write break point file: %v", err)
+	} else {
+		m.log.Debugf("Break points saved to %s", m.bpfilename)
+	}
+}
+
+// BreakPoints lists the breakpoints in address order
+func (m *Memory) BreakPoints() []*Breakpoint {
+	var bps []*Breakpoint
+	for _, de := range m.disassembly {
+		if me := m.getEntry(de.address); me.breakpoint != nil {
+			bps = append(bps, me.breakpoint)
 		}
 	}
-}
\ No newline at end of file
+	return bps
+}
+func (m *Memory) RemoveBreakPoint(address uint16) {
+	if me, found := m.getRootInstruction(address); found && me.breakpoint != nil {
+		m.log.Infof("Break point removed from %s", me.breakpoint)
+		me.breakpoint = nil
+		m.saveBreakPoints()
+		m.redraw(false)
+	}
+}
+
+// Instructions lists the address of every disassembled instruction
+func (m *Memory) Instructions() []uint16 {
+	addresses := make([]uint16, len(m.disassembly))
+	for i, de := range m.disassembly {
+		addresses[i] = de.address
+	}
+	return addresses
+}
+func (m *Memory) Size() int {
+	return m.size
+}
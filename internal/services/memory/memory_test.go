@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	m := New(logging.New(func(bool) {}), nil, nil, func(bool) {}, nil, nil, func() bool { return false }, nil)
+	m.memory = make([]*memoryEntry, 65536)
+	for i, b := range []byte{0x01, 0x00, 'H', 'i'} {
+		m.memory[0x0200 + i] = &memoryEntry{data: b}
+	}
+	for i, b := range []byte{'!', 0x00} {
+		m.memory[0x0300 + i] = &memoryEntry{data: b}
+	}
+	m.memory[0x0210] = &memoryEntry{void: true}
+	m.memory[0x0204] = &memoryEntry{data: 0xEA}
+	m.WriteMemory(0x0400, 0x00)
+
+	tests := []struct {
+		from    uint16
+		pattern []byte
+		want    uint16
+	}{
+		{0x0000, []byte{0x00}, 0x0201},
+		{0x0201, []byte{0x00}, 0x0301},
+		{0x0301, []byte{0x00}, 0x0400},
+		{0x0400, []byte{0x00}, 0x0201},
+		{0x0000, []byte("Hi"), 0x0202},
+		{0x0000, []byte{'i', 0xEA}, 0x0203},
+		// A pattern that isn't found leaves the cursor where it was
+		{0x0010, []byte{0x00, 0x00}, 0x0010},
+		{0x0010, []byte{0x00, '!'}, 0x0010},
+	}
+	for _, test := range tests {
+		m.Goto(test.from)
+		m.Search(test.pattern)
+		if got := m.cursorAddress(); got != test.want {
+			t.Errorf("Search(% X) from $%04X found $%04X, want $%04X", test.pattern, test.from, got, test.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	machine := func(name string) (int64, bool) {
+		if name == "pc" || name == "cursor" {
+			return 0x0600, true
+		}
+		return 0, false
+	}
+	m := New(logging.New(func(bool) {}), nil, nil, func(bool) {}, nil, nil, func() bool { return false }, machine)
+	m.Goto(0x0234)
+	tests := []struct {
+		text string
+		want uint16
+		ok   bool
+	}{
+		{"cursor", 0x0234, true},
+		{"pc + 2", 0x0602, true},
+		{"pc", 0x0600, true},
+		{"nope", 0, false},
+	}
+	for _, test := range tests {
+		got, err := expression.EvalAddressHex(test.text, m)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("EvalAddressHex(%q) = %04X, %v, want %04X ok %v", test.text, got, err, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/expression"
+	"strings"
+)
+
+// Watchpoint pauses the board when the driver services a read or write
+// within [Start, End], optionally only when the value transferred matches.
+type Watchpoint struct {
+	Start    uint16
+	End      uint16
+	Read     bool
+	Write    bool
+	HasValue bool
+	Value    uint8
+	Label    string
+	Enabled  bool
+	Hits     int
+}
+
+func (w *Watchpoint) matches(address uint16, data uint8, write bool) bool {
+	if !w.Enabled || address < w.Start || address > w.End {
+		return false
+	} else if write && !w.Write || !write && !w.Read {
+		return false
+	}
+	return !w.HasValue || w.Value == data
+}
+func (w *Watchpoint) String() string {
+	str := w.rangeString() + " " + w.access()
+	if w.HasValue {
+		str += " =$" + display.HexData(w.Value)
+	}
+	if w.Label != "" {
+		str += " (" + w.Label + ")"
+	}
+	return str
+}
+func (w *Watchpoint) rangeString() string {
+	str := "$" + display.HexAddress(w.Start)
+	if w.End != w.Start {
+		str += "..$" + display.HexAddress(w.End)
+	}
+	return str
+}
+func (w *Watchpoint) access() string {
+	switch {
+	case w.Read && w.Write:
+		return "rw"
+	case w.Read:
+		return "r"
+	}
+	return "w"
+}
+
+// ParseWatchpoint reads "start[..end] [r|w|rw] [=value]", where each number is an expression
+func ParseWatchpoint(text string, env expression.Env) (*Watchpoint, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no address given")
+	}
+	wp := &Watchpoint{Read: true, Write: true, Enabled: true}
+	bounds := strings.SplitN(fields[0], "..", 2)
+	var err error
+	if wp.Start, err = expression.EvalAddressHex(bounds[0], env); err != nil {
+		return nil, err
+	}
+	wp.End = wp.Start
+	if len(bounds) == 2 {
+		if wp.End, err = expression.EvalAddressHex(bounds[1], env); err != nil {
+			return nil, err
+		} else if wp.End < wp.Start {
+			return nil, fmt.Errorf("range end is before its start")
+		}
+	}
+	for _, field := range fields[1:] {
+		switch {
+		case field == "r", field == "w", field == "rw":
+			wp.Read = strings.Contains(field, "r")
+			wp.Write = strings.Contains(field, "w")
+		case strings.HasPrefix(field, "="):
+			value, err := expression.EvalHex(field[1:], env)
+			if err != nil {
+				return nil, err
+			} else if value < 0 || value > 0xFF {
+				return nil, fmt.Errorf("watch value out of range: %d", value)
+			}
+			wp.HasValue = true
+			wp.Value = uint8(value)
+		default:
+			return nil, fmt.Errorf("unexpected '%s'", field)
+		}
+	}
+	return wp, nil
+}
+
+func (m *Memory) AddWatchpoint(wp *Watchpoint) {
+	m.watchpoints = append(m.watchpoints, wp)
+	m.log.Infof("Watchpoint set on %s", wp)
+	m.saveBreakPoints()
+	m.redraw(false)
+}
+func (m *Memory) RemoveWatchpoint(wp *Watchpoint) {
+	for i, w := range m.watchpoints {
+		if w == wp {
+			m.watchpoints = append(m.watchpoints[:i], m.watchpoints[i+1:]...)
+			m.log.Infof("Watchpoint removed from %s", wp)
+			m.saveBreakPoints()
+			m.redraw(false)
+			return
+		}
+	}
+}
+func (m *Memory) Watchpoints() []*Watchpoint {
+	return m.watchpoints
+}
+
+// ToggleWatchpoint adds a read/write watch on a single byte, or removes any watch starting there
+func (m *Memory) ToggleWatchpoint(address uint16) {
+	for _, wp := range m.watchpoints {
+		if wp.Start == address {
+			m.RemoveWatchpoint(wp)
+			return
+		}
+	}
+	m.AddWatchpoint(&Watchpoint{Start: address, End: address, Read: true, Write: true, Enabled: true})
+}
+func (m *Memory) isWatched(address uint16) bool {
+	for _, wp := range m.watchpoints {
+		if wp.Enabled && address >= wp.Start && address <= wp.End {
+			return true
+		}
+	}
+	return false
+}
+func (m *Memory) checkWatchpoints(address uint16, data uint8, write bool) {
+	for _, wp := range m.watchpoints {
+		if wp.matches(address, data, write) {
+			wp.Hits++
+			access := "read"
+			if write {
+				access = "write"
+			}
+			m.watchHit = fmt.Sprintf("Watchpoint %s: %s of %s at %s", wp, access, display.HexData(data), display.HexAddress(address))
+			return
+		}
+	}
+}
+
+// WatchRead checks the watchpoints for a read, once the CPU takes the data on PHI2.
+// Writes are checked by WriteMemory, which is only called on PHI2.
+func (m *Memory) WatchRead(address uint16, data uint8) {
+	m.checkWatchpoints(address, data, false)
+}
+
+// WatchTriggered returns, and clears, the description of the access that last hit a watchpoint
+func (m *Memory) WatchTriggered() (string, bool) {
+	hit := m.watchHit
+	m.watchHit = ""
+	return hit, hit != ""
+}
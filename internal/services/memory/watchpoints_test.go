@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"testing"
+)
+
+type names map[string]int64
+
+func (n names) Lookup(name string) (int64, bool) {
+	value, ok := n[name]
+	return value, ok
+}
+
+func TestParseWatchpoint(t *testing.T) {
+	env := names{"cursor": 0x0300}
+	tests := []struct {
+		text string
+		want Watchpoint
+	}{
+		{"0200", Watchpoint{Start: 0x0200, End: 0x0200, Read: true, Write: true}},
+		{"$0200", Watchpoint{Start: 0x0200, End: 0x0200, Read: true, Write: true}},
+		{"#512 w", Watchpoint{Start: 0x0200, End: 0x0200, Write: true}},
+		{"0200..02FF r", Watchpoint{Start: 0x0200, End: 0x02FF, Read: true}},
+		{"0200..0200 rw", Watchpoint{Start: 0x0200, End: 0x0200, Read: true, Write: true}},
+		{"cursor..cursor+F w =FF", Watchpoint{Start: 0x0300, End: 0x030F, Write: true, HasValue: true, Value: 0xFF}},
+		{"D012 =#10", Watchpoint{Start: 0xD012, End: 0xD012, Read: true, Write: true, HasValue: true, Value: 10}},
+	}
+	for _, test := range tests {
+		got, err := ParseWatchpoint(test.text, env)
+		if err != nil {
+			t.Errorf("ParseWatchpoint(%q) failed: %v", test.text, err)
+			continue
+		}
+		test.want.Enabled = true
+		if *got != test.want {
+			t.Errorf("ParseWatchpoint(%q) = %+v, want %+v", test.text, *got, test.want)
+		}
+	}
+}
+
+func TestParseWatchpointErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"10000",
+		"0300..0200",
+		"0200 x",
+		"0200 =100",
+		"0200 =",
+		"unknown",
+		"0200..",
+	}
+	for _, text := range tests {
+		if wp, err := ParseWatchpoint(text, names{}); err == nil {
+			t.Errorf("ParseWatchpoint(%q) = %s, want an error", text, wp)
+		}
+	}
+}
+
+func TestWatchpointMatches(t *testing.T) {
+	wp := &Watchpoint{Start: 0x0200, End: 0x020F, Write: true, HasValue: true, Value: 0x42, Enabled: true}
+	tests := []struct {
+		address uint16
+		data    uint8
+		write   bool
+		want    bool
+	}{
+		{0x0200, 0x42, true, true},
+		{0x020F, 0x42, true, true},
+		{0x0210, 0x42, true, false},
+		{0x01FF, 0x42, true, false},
+		{0x0200, 0x41, true, false},
+		{0x0200, 0x42, false, false},
+	}
+	for _, test := range tests {
+		if got := wp.matches(test.address, test.data, test.write); got != test.want {
+			t.Errorf("matches($%04X, $%02X, write %v) = %v, want %v", test.address, test.data, test.write, got, test.want)
+		}
+	}
+	wp.Enabled = false
+	if wp.matches(0x0200, 0x42, true) {
+		t.Errorf("disabled watchpoint matched")
+	}
+}
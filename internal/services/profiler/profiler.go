@@ -0,0 +1,229 @@
+package profiler
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	sortCycles = iota
+	sortCalls
+	sortAverage
+)
+
+var sortNames = []string{"cycles", "calls", "average"}
+
+// Entry accumulates the cycles spent executing an instruction address or an opcode
+type Entry struct {
+	Address uint16
+	OpCode  uint8
+	Name    string
+	Calls   uint64
+	Cycles  uint64
+}
+
+func (e *Entry) Average() float64 {
+	if e.Calls == 0 {
+		return 0
+	}
+	return float64(e.Cycles) / float64(e.Calls)
+}
+
+// Profiler attributes every clock cycle to the instruction being executed,
+// both by its address and by its opcode
+type Profiler struct {
+	byAddress map[uint16]*Entry
+	byOpCode  map[uint8]*Entry
+	current   *Entry
+	currentOp *Entry
+	total     uint64
+	log       *logging.Log
+	redraw    func(bool)
+	keys      *keymap.Keymap
+	sortBy    int
+	opCodes   bool
+	list      common.List
+	sync      sync.Mutex
+}
+
+func New(log *logging.Log, redraw func(bool), keys *keymap.Keymap) *Profiler {
+	p := &Profiler{
+		log:    log,
+		redraw: redraw,
+		keys:   keys,
+	}
+	p.Reset()
+	return p
+}
+
+func (p *Profiler) Reset() {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	p.byAddress = map[uint16]*Entry{}
+	p.byOpCode = map[uint8]*Entry{}
+	p.current, p.currentOp = nil, nil
+	p.total = 0
+	p.list.Top()
+}
+
+// Fetch is called as each instruction is fetched
+func (p *Profiler) Fetch(address uint16, opCode uint8, name string) {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	if p.current = p.byAddress[address]; p.current == nil {
+		p.current = &Entry{Address: address, OpCode: opCode, Name: name}
+		p.byAddress[address] = p.current
+	}
+	p.current.OpCode, p.current.Name = opCode, name
+	p.current.Calls++
+	if p.currentOp = p.byOpCode[opCode]; p.currentOp == nil {
+		p.currentOp = &Entry{OpCode: opCode, Name: name}
+		p.byOpCode[opCode] = p.currentOp
+	}
+	p.currentOp.Calls++
+}
+
+// Cycle is called once per clock cycle, charging it to the current instruction
+func (p *Profiler) Cycle() {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	if p.current == nil {
+		return
+	}
+	p.current.Cycles++
+	p.currentOp.Cycles++
+	p.total++
+}
+
+// Entries returns a copy of the profile, by address or by opcode, in the current sort
+// order, with the total cycle count it was taken at
+func (p *Profiler) Entries(byOpCode bool) ([]Entry, uint64) {
+	p.sync.Lock()
+	defer p.sync.Unlock()
+	var entries []Entry
+	if byOpCode {
+		for _, e := range p.byOpCode {
+			entries = append(entries, *e)
+		}
+	} else {
+		for _, e := range p.byAddress {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch p.sortBy {
+		case sortCalls:
+			if a.Calls != b.Calls {
+				return a.Calls > b.Calls
+			}
+		case sortAverage:
+			if a.Average() != b.Average() {
+				return a.Average() > b.Average()
+			}
+		}
+		if a.Cycles != b.Cycles {
+			return a.Cycles > b.Cycles
+		}
+		return a.Address < b.Address
+	})
+	return entries, p.total
+}
+
+func percent(cycles uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(cycles) * 100 / float64(total)
+}
+func (p *Profiler) format(e Entry, byOpCode bool, total uint64) string {
+	where := "$" + display.HexAddress(e.Address)
+	if byOpCode {
+		where = "     "
+	}
+	return fmt.Sprintf("%6.2f%% %10d %9d %7.2f  %s  $%s  %s", percent(e.Cycles, total), e.Cycles, e.Calls, e.Average(), where, display.HexData(e.OpCode), e.Name)
+}
+
+// Export writes a flat profile, by address then by opcode, to a text file
+func (p *Profiler) Export(filename string) error {
+	var sb strings.Builder
+	entries, total := p.Entries(false)
+	fmt.Fprintf(&sb, "Flat profile: %d cycle(s) sorted by %s\n\n", total, sortNames[p.sortBy])
+	header := "  %time     cycles     calls     avg  addr   op   name\n"
+	sb.WriteString(header)
+	for _, e := range entries {
+		sb.WriteString(p.format(e, false, total) + "\n")
+	}
+	sb.WriteString("\nBy opcode\n\n" + header)
+	entries, total = p.Entries(true)
+	for _, e := range entries {
+		sb.WriteString(p.format(e, true, total) + "\n")
+	}
+	return ioutil.WriteFile(filename, []byte(sb.String()), 0644)
+}
+
+func (p *Profiler) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	t.HideCursor()
+	entries, total := p.Entries(p.opCodes)
+	p.list.Layout(t.Rows() - 4, len(entries))
+
+	view := "address"
+	if p.opCodes {
+		view = "opcode"
+	}
+	t.PrintAtf(1, 1, "%sProfile by %s: %d cycle(s), sorted by %s%s%s", common.Yellow, view, total, sortNames[p.sortBy], common.Reset, display.ClearEnd)
+	t.PrintAtf(1, 2, "%s  %%time     cycles     calls     avg  addr   op   name%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	for row := 0; row < p.list.Rows; row++ {
+		line := ""
+		if i, ok := p.list.Item(row); ok {
+			line = common.White + p.format(entries[i], p.opCodes, total) + common.Reset
+		}
+		t.PrintAtf(1, row+3, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, p.keys.Help(keymap.Profiler, "sort_cycles", "sort_calls", "sort_average", "view", "export", "reset") + ", any other key to exit")
+}
+func (p *Profiler) Process(input common.Input) bool {
+	action := p.keys.Action(keymap.Profiler, input)
+	switch action {
+	case "up", "down", "page_up", "page_down":
+		p.list.Move(action)
+	case "sort_cycles":
+		p.sortBy = sortCycles
+		p.list.Top()
+	case "sort_calls":
+		p.sortBy = sortCalls
+		p.list.Top()
+	case "sort_average":
+		p.sortBy = sortAverage
+		p.list.Top()
+	case "view":
+		p.opCodes = !p.opCodes
+		p.list.Top()
+	case "reset":
+		p.Reset()
+		p.log.Info("Profile reset")
+	case "export":
+		filename := strings.TrimSuffix(config.CLIConfig.RomFile, filepath.Ext(config.CLIConfig.RomFile)) + ".profile.txt"
+		if err := p.Export(filename); err != nil {
+			p.log.Warnf("Profile export failed: %v", err)
+		} else {
+			p.log.Infof("Profile written to %s", filename)
+		}
+	default:
+		return true
+	}
+	p.redraw(true)
+	return false
+}
@@ -0,0 +1,95 @@
+package query
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+)
+
+// Query searches the microcode with an expression typed at its prompt, and
+// jumps to a match in the control line editor
+type Query struct {
+	opCodes *instructionSet.OpCodes
+	log     *logging.Log
+	redraw  func(bool)
+	jump    func(m *instructionSet.Match)
+	input   string
+	ran     string
+	matches []*instructionSet.Match
+	err     error
+	list    common.List
+}
+
+func New(log *logging.Log, opCodes *instructionSet.OpCodes, redraw func(bool), jump func(m *instructionSet.Match)) *Query {
+	return &Query{
+		opCodes: opCodes,
+		log:     log,
+		redraw:  redraw,
+		jump:    jump,
+		list:    common.List{Select: true},
+	}
+}
+
+func (q *Query) run() {
+	q.ran = q.input
+	q.matches, q.err = q.opCodes.Query(q.input)
+	q.list.Top()
+}
+
+func (q *Query) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	q.list.Layout(t.Rows() - 5, len(q.matches))
+
+	status := ""
+	switch {
+	case q.err != nil:
+		status = fmt.Sprintf("%s%v", common.BrightRed, q.err)
+	case q.ran != "":
+		status = fmt.Sprintf("%s%d match(es) for %s", common.White, len(q.matches), q.ran)
+	default:
+		status = common.Grey + "Lines by mnemonic, phi1, phi2, step, N V Z C, mode == ABX, joined with and, or, not"
+	}
+	t.PrintAtf(1, 1, "%sMicrocode query%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	t.PrintAtf(1, 3, "%s%s%s", status, common.Reset, display.ClearEnd)
+	for row := 0; row < q.list.Rows; row++ {
+		line := ""
+		if i, ok := q.list.Item(row); ok {
+			colour := common.White
+			if i == q.list.Cursor {
+				colour = common.BGBlue + common.BrightWhite
+			}
+			line = colour + q.matches[i].String() + common.Reset
+		}
+		t.PrintAtf(1, row + 4, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, "enter search or jump to match, esc exit")
+	t.PrintAtf(1, 2, "%sQuery: %s%s%s", common.Yellow, common.White, q.input, display.ClearEnd)
+	t.ShowCursor()
+}
+func (q *Query) Process(input common.Input) bool {
+	switch {
+	case q.list.Scroll(input):
+	case input.KeyCode != 0:
+	case input.Ascii == 27:
+		return true
+	case input.Ascii == 127:
+		if len(q.input) > 0 {
+			q.input = q.input[:len(q.input)-1]
+		}
+	case input.Ascii == 13:
+		if q.input != q.ran || q.err != nil {
+			q.run()
+		} else if q.list.Cursor < len(q.matches) {
+			q.jump(q.matches[q.list.Cursor])
+			return true
+		}
+	case input.Ascii >= 32 && input.Ascii < 127:
+		q.input += string(rune(input.Ascii))
+	}
+	q.redraw(false)
+	return false
+}
@@ -0,0 +1,217 @@
+package serial
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"time"
+)
+
+// In framed mode every message, in either direction, is wrapped as
+//
+//   SOF (0x7E), length, sequence, payload..., CRC-16 high, CRC-16 low
+//
+// where the payload is the command without its leading length byte, and the
+// CRC (CCITT, initial value $FFFF) covers the length, sequence and payload.
+// Replies carry the sequence number of the request they answer; unsolicited
+// events (clock, IRQ, NMI, reset) use sequence 0. Commands without a reply
+// are acknowledged with a 'k' frame.
+const (
+	frameStart    = 0x7E
+	frameOverhead = 5
+)
+
+// Stats counts the traffic and errors seen on the current connection
+type Stats struct {
+	Frames      int
+	CrcErrors   int
+	Dropped     int
+	Stale       int
+	Timeouts    int
+	Retransmits int
+}
+
+// send writes a command, framing it with the given sequence number when framed
+// mode is active
+func (s *Serial) send(cmd []byte, seq uint8) error {
+	s.traffic.sent(cmd)
+	bs := cmd
+	if s.isFramed() {
+		payload := cmd[1:]
+		bs = make([]byte, 0, len(payload)+frameOverhead)
+		bs = append(bs, frameStart, uint8(len(payload)), seq)
+		bs = append(bs, payload...)
+		crc := crc16(bs[1:])
+		bs = append(bs, uint8(crc >> 8), uint8(crc))
+	}
+	if n, err := s.port.Write(bs); err != nil {
+		return err
+	} else if n != len(bs) {
+		return fmt.Errorf("unexpected number of bytes sent.  Expected %d, sent: %d", len(bs), n)
+	}
+	return nil
+}
+
+// nextSeq numbers a framed request, skipping 0 which is reserved for events. Raw
+// requests and their replies are all sequence 0. Called with the exchange lock held.
+func (s *Serial) nextSeq() uint8 {
+	if !s.isFramed() {
+		return 0
+	}
+	s.seq++
+	if s.seq == 0 {
+		s.seq = 1
+	}
+	return s.seq
+}
+
+// exchange sends a command and waits for its reply on the given channel. The
+// lock is held for the whole request/reply cycle so that requests from
+// different goroutines can't take each other's replies. In framed mode a
+// request that times out is retransmitted, with a new sequence number so that
+// a late reply to the earlier attempt is discarded.
+func (s *Serial) exchange(name string, cmd []byte, replies chan reply) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	framed := s.isFramed()
+	if replies == s.ack && !framed {
+		// Only framed mode acknowledges commands without a reply
+		if err := s.send(cmd, 0); err != nil {
+			s.log.Errorf("Failed to send request for %s: %v", name, err)
+			return nil, false
+		}
+		return nil, true
+	}
+	attempts := 1
+	if framed {
+		attempts += config.CLIConfig.Serial.Retries
+	}
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			s.count(&s.stats.Retransmits)
+			s.log.Debugf("Retransmitting request for %s", name)
+		}
+		seq := s.nextSeq()
+		if err := s.send(cmd, seq); err != nil {
+			s.log.Errorf("Failed to send request for %s: %v", name, err)
+			return nil, false
+		}
+		if bs, ok := s.await(replies, seq, time.After(s.timeout())); ok {
+			return bs, true
+		}
+		s.count(&s.stats.Timeouts)
+	}
+	s.log.Warnf("%s not received", name)
+	return nil, false
+}
+
+// await waits for the reply carrying the expected sequence number, discarding
+// replies to earlier requests
+func (s *Serial) await(replies chan reply, seq uint8, timeout <-chan time.Time) ([]byte, bool) {
+	for {
+		select {
+		case r := <-replies:
+			if r.seq == seq {
+				return r.bs, true
+			}
+			s.count(&s.stats.Stale)
+			s.log.Debugf("Discarding reply to stale request %d", r.seq)
+		case <- timeout:
+			return nil, false
+		}
+	}
+}
+func (s *Serial) timeout() time.Duration {
+	if ms := config.CLIConfig.Serial.TimeoutMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return 5 * time.Second
+}
+
+// deframe collects inbound bytes into frames, forwarding the payload of each
+// valid frame to the driver tagged with its sequence number. Bytes outside a frame are dropped, and after a CRC
+// failure the search for the next frame resumes just past the bad start byte.
+// Called from readPort with the framing lock held.
+func (s *Serial) deframe(b byte) {
+	if len(s.frame) == 0 {
+		if b == frameStart {
+			s.frame = append(s.frame, b)
+		} else {
+			s.count(&s.stats.Dropped)
+		}
+		return
+	}
+	s.frame = append(s.frame, b)
+	if len(s.frame) < 2 {
+		return
+	}
+	size := int(s.frame[1]) + frameOverhead
+	if len(s.frame) < size {
+		return
+	}
+
+	frame := s.frame
+	s.frame = nil
+	if crc := crc16(frame[1:size-2]); crc != uint16(frame[size-2]) << 8 | uint16(frame[size-1]) {
+		s.count(&s.stats.CrcErrors)
+		s.log.Debugf("Frame failed CRC check: % X", frame)
+		s.count(&s.stats.Dropped)
+		for _, rb := range frame[1:] {
+			s.deframe(rb)
+		}
+		return
+	}
+	s.count(&s.stats.Frames)
+	for _, p := range frame[3:size-2] {
+		s.buffer <- inbound{b: p, seq: frame[2]}
+	}
+}
+
+func (s *Serial) isFramed() bool {
+	s.framing.Lock()
+	defer s.framing.Unlock()
+	return s.framed
+}
+
+// setFramed switches protocol, discarding any partly received frame
+func (s *Serial) setFramed(framed bool) {
+	s.framing.Lock()
+	defer s.framing.Unlock()
+	s.framed = framed
+	s.frame = nil
+}
+
+func crc16(bs []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range bs {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc & 0x8000 != 0 {
+				crc = crc << 1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// count adds one to a statistic. The exchange and deframing paths hold different
+// locks, so the statistics have their own.
+func (s *Serial) count(stat *int) {
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+	*stat++
+}
+
+// Stats gives a copy of the statistics for the current connection
+func (s *Serial) Stats() Stats {
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+	return s.stats
+}
+func (s *Serial) resetStats() {
+	s.statsLock.Lock()
+	defer s.statsLock.Unlock()
+	s.stats = Stats{}
+}
@@ -0,0 +1,116 @@
+package serial
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"testing"
+)
+
+func TestCrc16(t *testing.T) {
+	tests := []struct {
+		bs   []byte
+		want uint16
+	}{
+		{nil, 0xFFFF},
+		{[]byte("123456789"), 0x29B1},
+		{[]byte{0x00}, 0xE1F0},
+	}
+	for _, test := range tests {
+		if got := crc16(test.bs); got != test.want {
+			t.Errorf("crc16(% X) = %04X, want %04X", test.bs, got, test.want)
+		}
+	}
+}
+
+// frame wraps a payload the way the firmware does
+func frame(seq uint8, payload ...byte) []byte {
+	bs := append([]byte{frameStart, uint8(len(payload)), seq}, payload...)
+	crc := crc16(bs[1:])
+	return append(bs, uint8(crc >> 8), uint8(crc))
+}
+
+func join(parts ...[]byte) []byte {
+	var bs []byte
+	for _, p := range parts {
+		bs = append(bs, p...)
+	}
+	return bs
+}
+
+func corrupt(bs []byte) []byte {
+	bad := append([]byte(nil), bs...)
+	bad[len(bad)-1] ^= 0xFF
+	return bad
+}
+
+func TestDeframe(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        []byte
+		want      []inbound
+		frames    int
+		crcErrors int
+		dropped   int
+	}{
+		{
+			name:   "single frame",
+			in:     frame(3, 'A', 0x12, 0x34),
+			want:   []inbound{{'A', 3}, {0x12, 3}, {0x34, 3}},
+			frames: 1,
+		},
+		{
+			name:   "event and reply",
+			in:     join(frame(0, 'c'), frame(7, 'd', 0xEA)),
+			want:   []inbound{{'c', 0}, {'d', 7}, {0xEA, 7}},
+			frames: 2,
+		},
+		{
+			name:    "noise before a frame",
+			in:      join([]byte{0x00, 0x41}, frame(1, 'k')),
+			want:    []inbound{{'k', 1}},
+			frames:  1,
+			dropped: 2,
+		},
+		{
+			name:   "payload containing the start byte",
+			in:     frame(2, 'd', frameStart),
+			want:   []inbound{{'d', 2}, {frameStart, 2}},
+			frames: 1,
+		},
+		{
+			name:      "bad crc then a good frame",
+			in:        join(corrupt(frame(4, 'a', 0x00, 0x02)), frame(5, 'k')),
+			want:      []inbound{{'k', 5}},
+			frames:    1,
+			crcErrors: 1,
+			dropped:   8,
+		},
+		{
+			name: "partial frame",
+			in:   frame(6, 'o', 0xA9)[:4],
+		},
+	}
+	for _, test := range tests {
+		s := &Serial{buffer: make(chan inbound, 64), log: logging.New(func(bool) {})}
+		for _, b := range test.in {
+			s.deframe(b)
+		}
+		close(s.buffer)
+		var got []inbound
+		for in := range s.buffer {
+			got = append(got, in)
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		} else {
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+					break
+				}
+			}
+		}
+		if st := s.Stats(); st.Frames != test.frames || st.CrcErrors != test.crcErrors || st.Dropped != test.dropped {
+			t.Errorf("%s: stats %+v, want %d frame(s), %d crc error(s), %d dropped", test.name, st, test.frames, test.crcErrors, test.dropped)
+		}
+	}
+}
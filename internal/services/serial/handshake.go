@@ -0,0 +1,124 @@
+package serial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the version of the command set spoken by this host
+const ProtocolVersion = 1
+
+var (
+	Hello = []byte {0x01, 'h'}
+
+	// Commands understood by firmware that predates the handshake
+	legacyCommands = []byte{'o', 'a', 'd', 's', 'L', 'D'}
+	// Commands the host cannot work without
+	requiredCommands = []byte{'o', 'a', 'd', 's', 'L', 'D'}
+)
+
+// Firmware describes the board as reported by the hello exchange. The reply is
+// 'h', major, minor, protocol, command count, followed by one byte per command.
+type Firmware struct {
+	Major    uint8
+	Minor    uint8
+	Protocol uint8
+	Commands []byte
+	Legacy   bool
+}
+
+func (f *Firmware) String() string {
+	if f.Legacy {
+		return "legacy firmware (no handshake)"
+	}
+	return fmt.Sprintf("firmware %d.%d, protocol %d", f.Major, f.Minor, f.Protocol)
+}
+func (f *Firmware) supports(command byte) bool {
+	for _, c := range f.Commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// errNoHello means the board didn't answer hello in time
+var errNoHello = errors.New("no reply to hello")
+
+// handshake says hello to a newly opened port and only reports the connection
+// once the firmware is known to be compatible. Firmware that doesn't answer is
+// treated as speaking the legacy command set, but a port that can't be written
+// to is closed.
+func (s *Serial) handshake() {
+	port := s.port
+	firmware, err := s.sayHello()
+	if err == errNoHello && s.isFramed() {
+		s.log.Warnf("No framed reply to hello. Falling back to the raw protocol")
+		s.setFramed(false)
+		firmware, err = s.sayHello()
+	}
+	if err == errNoHello {
+		firmware = &Firmware{Commands: legacyCommands, Legacy: true}
+		s.log.Warnf("No reply to hello. Assuming legacy protocol")
+	} else if err != nil {
+		s.log.Errorf("Failed to send hello to %s: %v", s.portName, err)
+		port.Close()
+		return
+	}
+
+	if reason, ok := firmware.compatible(); !ok {
+		s.log.Errorf("Refusing %s on %s: %s", firmware, s.portName, reason)
+		s.refused = s.portName
+		port.Close()
+		if s.connected {
+			s.connected = false
+			s.connStatus(false)
+		}
+		return
+	}
+	s.firmware = firmware
+	s.log.Infof("Connected to %s", firmware)
+	if !s.connected {
+		s.connStatus(true)
+		s.connected = true
+	}
+}
+func (s *Serial) sayHello() (*Firmware, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seq := s.nextSeq()
+	if err := s.send(Hello, seq); err != nil {
+		return nil, err
+	}
+	bs, ok := s.await(s.hello, seq, time.After(time.Second))
+	if !ok {
+		return nil, errNoHello
+	}
+	return &Firmware{Major: bs[0], Minor: bs[1], Protocol: bs[2], Commands: bs[4:]}, nil
+}
+func (f *Firmware) compatible() (string, bool) {
+	if f.Protocol > ProtocolVersion {
+		return fmt.Sprintf("protocol %d is newer than the host's protocol %d", f.Protocol, ProtocolVersion), false
+	}
+	var missing []string
+	for _, c := range requiredCommands {
+		if !f.supports(c) {
+			missing = append(missing, string(rune(c)))
+		}
+	}
+	if len(missing) > 0 {
+		return "missing command(s) " + strings.Join(missing, " "), false
+	}
+	return "", true
+}
+
+// Supports reports whether the connected firmware understands a command
+func (s *Serial) Supports(command byte) bool {
+	return s.firmware != nil && s.firmware.supports(command)
+}
+func (s *Serial) Firmware() *Firmware {
+	return s.firmware
+}
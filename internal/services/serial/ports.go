@@ -0,0 +1,105 @@
+package serial
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	srl "go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const byIdDir = "/dev/serial/by-id"
+
+// PortInfo describes a candidate serial port
+type PortInfo struct {
+	Name    string
+	Link    string
+	IsUSB   bool
+	VID     string
+	PID     string
+	Product string
+}
+
+// candidatePatterns match the device names a USB serial adapter shows up as on Linux and macOS
+var candidatePatterns = []string{"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/cu.*", "/dev/tty.usb*", "COM*"}
+
+// ListPorts enumerates the serial ports that could be the board, with their USB
+// VID/PID where the OS reports them and any /dev/serial/by-id link to them
+func ListPorts() ([]PortInfo, error) {
+	var ports []PortInfo
+	if details, err := enumerator.GetDetailedPortsList(); err == nil {
+		for _, d := range details {
+			ports = append(ports, PortInfo{Name: d.Name, IsUSB: d.IsUSB, VID: d.VID, PID: d.PID, Product: d.Product})
+		}
+	} else if names, err := srl.GetPortsList(); err == nil {
+		for _, name := range names {
+			ports = append(ports, PortInfo{Name: name})
+		}
+	} else {
+		return nil, err
+	}
+
+	links := byIdLinks()
+	var candidates []PortInfo
+	for _, port := range ports {
+		port.Link = links[port.Name]
+		if port.IsUSB || port.Link != "" || isCandidate(port.Name) {
+			candidates = append(candidates, port)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates, nil
+}
+func isCandidate(name string) bool {
+	for _, pattern := range candidatePatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// byIdLinks maps device names to their stable /dev/serial/by-id links
+func byIdLinks() map[string]string {
+	links := map[string]string{}
+	matches, _ := filepath.Glob(filepath.Join(byIdDir, "*"))
+	for _, link := range matches {
+		if target, err := filepath.EvalSymlinks(link); err == nil {
+			links[target] = link
+		}
+	}
+	return links
+}
+
+// resolvePortName expands a configured port name, which may be a glob such as
+// /dev/ttyUSB* or /dev/serial/by-id/usb-FTDI*, to the first matching device
+func resolvePortName() string {
+	name := config.CLIConfig.Serial.PortName
+	if !strings.ContainsAny(name, "*?[") {
+		return name
+	}
+	matches, _ := filepath.Glob(name)
+	if len(matches) == 0 {
+		return name
+	}
+	sort.Strings(matches)
+	return matches[0]
+}
+
+// SelectPort switches to a different port, dropping the current connection, and saves the choice
+func (s *Serial) SelectPort(name string) {
+	config.CLIConfig.Serial.PortName = name
+	s.portName = resolvePortName()
+	s.refused = ""
+	if s.port != nil {
+		s.port.Close()
+	}
+	if err := config.SavePortName(name); err != nil {
+		s.log.Warnf("Port %s selected but not saved: %v", name, err)
+	} else {
+		s.log.Infof("Port %s selected", name)
+	}
+}
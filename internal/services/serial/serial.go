@@ -7,11 +7,14 @@ import (
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/status"
 	srl "go.bug.st/serial"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,19 +25,49 @@ var (
 	GetStatus  = []byte {0x01, 's'}
 	SetLines   = []byte {0x07, 'L'}
 	SetData    = []byte {0x02, 'D'}
+	GetRegisters = []byte {0x01, 'x'}
+	Tick       = []byte {0x07, 'T'}
+	ClockCtrl  = []byte {0x06, 'K'}
+	Interrupt  = []byte {0x03, 'Q'}
 	//GetClock   = []byte {0x01, 'c'}
 	//GetIRQ     = []byte {0x01, 'i'}
 	//GetNMI     = []byte {0x01, 'n'}
 	//GetReset   = []byte {0x01, 'r'}
 )
 
+// inbound is a received byte and the sequence number of the frame that carried it
+type inbound struct {
+	b   byte
+	seq uint8
+}
+
+// reply is the body of a reply to a request, without its command byte
+type reply struct {
+	seq uint8
+	bs  []byte
+}
+
 type Serial struct {
 	port         srl.Port
-	buffer       chan byte
-	address      chan []byte
-	opCode       chan byte
-	data         chan byte
-	status       chan byte
+	buffer       chan inbound
+	address      chan reply
+	opCode       chan reply
+	data         chan reply
+	status       chan reply
+	registers    chan reply
+	tick         chan reply
+	hello        chan reply
+	ack          chan reply
+	lock         sync.Mutex
+	edgeStatus   int32
+	seq          uint8
+	framing      sync.Mutex
+	framed       bool
+	frame        []byte
+	stats        Stats
+	statsLock    sync.Mutex
+	firmware     *Firmware
+	refused      string
 	terminated   bool
 	connected    bool
 	steps        *status.Steps
@@ -45,11 +78,18 @@ type Serial struct {
 	reset        *status.Reset
 	log          *logging.Log
 	connStatus   func(bool)
+	redraw       func(bool)
+	keys         *keymap.Keymap
+	portName     string
+	ports        []PortInfo
+	cursor       int
+	traffic      *Traffic
+	inbound      []byte
 	startCapture func()
 	stopCapture  func()
 	mode         *srl.Mode
 }
-func New(log *logging.Log, clock *status.Clock, irq *status.Irq, nmi *status.Nmi, reset *status.Reset, flags *status.Flags, steps *status.Steps, connStatus func(bool), wg *sync.WaitGroup) *Serial {
+func New(log *logging.Log, clock *status.Clock, irq *status.Irq, nmi *status.Nmi, reset *status.Reset, flags *status.Flags, steps *status.Steps, connStatus func(bool), redraw func(bool), keys *keymap.Keymap, wg *sync.WaitGroup) *Serial {
 	s := &Serial{
 		clock:      clock,
 		irq:        irq,
@@ -59,13 +99,20 @@ func New(log *logging.Log, clock *status.Clock, irq *status.Irq, nmi *status.Nmi
 		flags:      flags,
 		log:        log,
 		terminated: false,
+		edgeStatus: -1,
 		connected:  false,
 		connStatus: connStatus,
-		buffer:     make(chan byte),
-		address:    make(chan []byte),
-		data:       make(chan byte),
-		status:     make(chan byte),
-		opCode:     make(chan byte),
+		redraw:     redraw,
+		keys:       keys,
+		buffer:     make(chan inbound),
+		address:    make(chan reply, 1),
+		data:       make(chan reply, 1),
+		status:     make(chan reply, 1),
+		opCode:     make(chan reply, 1),
+		registers:  make(chan reply, 1),
+		tick:       make(chan reply, 1),
+		hello:      make(chan reply, 1),
+		ack:        make(chan reply, 1),
 		mode:       &srl.Mode {
 			DataBits: config.CLIConfig.Serial.DataBits,
 			BaudRate: config.CLIConfig.Serial.BaudRate,
@@ -74,6 +121,7 @@ func New(log *logging.Log, clock *status.Clock, irq *status.Irq, nmi *status.Nmi
 		},
 	}
 
+	s.traffic = newTraffic(s)
 	go s.driver(wg)
 	go s.portMonitor(wg)
 
@@ -115,26 +163,12 @@ func (s *Serial) ReadAddress() (uint16, bool) {
 		return 0, false
 	}
 
-	// Send command
-	if n, err := s.port.Write(GetAddress); err != nil {
-		s.log.Errorf("Failed to send request for address: %v", err)
-		return 0, false
-	} else if n != 2 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 2, sent: %d", n)
-		return 0, false
-	}
-
-	// Receive address
-	a := uint16(0)
-	bs := make([]byte, 0, 2)
-	select {
-	case bs = <-s.address:
-		a = binary.LittleEndian.Uint16(bs)
-		s.log.Tracef("Received address: %s", display.HexAddress(a))
-	case <- time.After(5 * time.Second):
-		s.log.Warnf("Address not received")
+	bs, ok := s.exchange("address", GetAddress, s.address)
+	if !ok {
 		return 0, false
 	}
+	a := binary.LittleEndian.Uint16(bs)
+	s.log.Tracef("Received address: %s", display.HexAddress(a))
 	return a, true
 }
 func (s *Serial) ReadOpCode() (uint8, bool) {
@@ -142,92 +176,57 @@ func (s *Serial) ReadOpCode() (uint8, bool) {
 		return 0, false
 	}
 
-	// Send command
-	if n, err := s.port.Write(GetOpCode); err != nil {
-		s.log.Errorf("Failed to send request for OpCode: %v", err)
-		return 0, false
-	} else if n != 2 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 2, sent: %d", n)
-		return 0, false
-	}
-
-	// Receive opCode
-	select {
-	case b := <-s.opCode:
-		s.log.Tracef("OpCode received %s", display.HexData(b))
-		return b, true
-	case <- time.After(5 * time.Second):
-		s.log.Warnf("OpCode not received")
+	bs, ok := s.exchange("OpCode", GetOpCode, s.opCode)
+	if !ok {
 		return 0, false
 	}
+	s.log.Tracef("OpCode received %s", display.HexData(bs[0]))
+	return bs[0], true
 }
 func (s *Serial) ReadData() (uint8, bool) {
 	if !s.connected {
 		return 0, false
 	}
 
-	// Send command
-	if n, err := s.port.Write(GetData); err != nil {
-		s.log.Errorf("Failed to send request for data: %v", err)
-		return 0, false
-	} else if n != 2 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 2, sent: %d", n)
-		return 0, false
-	}
-
-	// Receive data
-	select {
-	case b := <-s.data:
-		e := <-s.data
-		s.log.Tracef("Received data: %s. Error code: %s", display.HexData(b), display.HexData(e))
-		return b, e == 0
-	case <- time.After(5 * time.Second):
-		s.log.Warnf("Data not received")
+	bs, ok := s.exchange("data", GetData, s.data)
+	if !ok {
 		return 0, false
 	}
+	s.log.Tracef("Received data: %s. Error code: %s", display.HexData(bs[0]), display.HexData(bs[1]))
+	return bs[0], bs[1] == 0
 }
 func (s *Serial) ReadStatus() (uint8, bool) {
 	if !s.connected {
 		return 0, false
 	}
 
-	// Send command
-	if n, err := s.port.Write(GetStatus); err != nil {
-		s.log.Errorf("Failed to send request for status: %v", err)
-		return 0, false
-	} else if n != 2 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 2, sent: %d", n)
+	bs, ok := s.exchange("status", GetStatus, s.status)
+	if !ok {
 		return 0, false
 	}
+	s.log.Tracef("Status received %s", display.BinData(bs[0]))
+	return bs[0], true
+}
 
-	// Receive status
-	select {
-		case b := <-s.status:
-			s.log.Tracef("Status received %s", display.BinData(b))
-			return b, true
-		case <- time.After(5 * time.Second):
-			s.log.Warnf("Status not received")
-			return 0, false
+// ReadRegisters requests the A, X, Y, SP, PCL and PCH latches from firmware that supports it
+func (s *Serial) ReadRegisters() ([]byte, bool) {
+	if !s.connected || !s.Supports('x') {
+		return nil, false
+	}
+
+	bs, ok := s.exchange("registers", GetRegisters, s.registers)
+	if ok {
+		s.log.Tracef("Registers received % X", bs)
 	}
+	return bs, ok
 }
 func (s *Serial) SetData(data uint8) bool {
 	if !s.connected {
 		return false
 	}
-	// Send command
 	s.log.Debugf("Sending data: %s", display.HexData(data))
-	bs := append(SetData, data)
-	if n, err := s.port.Write(bs); err != nil {
-		s.log.Errorf("Failed to send request to set data: %v", err)
-		return false
-	} else if n != 3 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 3, sent: %d", n)
-		return false
-	}
-
-	// Read response
-	e := <-s.data
-	return e == 0
+	bs, ok := s.exchange("data acknowledgement", append(SetData, data), s.data)
+	return ok && bs[0] == 0
 }
 func (s *Serial) SetLines(data uint64, breakpoint bool) (uint8, bool) {
 	if !s.connected {
@@ -237,20 +236,83 @@ func (s *Serial) SetLines(data uint64, breakpoint bool) (uint8, bool) {
 	if breakpoint {
 		data = data ^ instructionSet.CL_PAUS
 	}
-	// Send command
 	bs := append(SetLines, uint8(data >> 40), uint8(data >> 32), uint8(data >> 24), uint8(data >> 16), uint8(data >> 8), uint8(data))
 	s.log.Tracef("%c [%s %s %s %s %s %s] %s", bs[0], display.HexData(bs[1]), display.HexData(bs[2]), display.HexData(bs[3]), display.HexData(bs[4]), display.HexData(bs[5]), display.HexData(bs[6]), display.HexData(bs[7]) )
-	if n, err := s.port.Write(bs); err != nil {
-		s.log.Errorf("Failed to send request to set lines: %v", err)
-		return 0, false
-	} else if n != 8 {
-		s.log.Errorf("Unexpected number of bytes sent.  Expected 8, sent: %d", n)
+	if _, ok := s.exchange("lines acknowledgement", bs, s.ack); !ok {
 		return 0, false
 	}
 
 	return s.ReadStatus()
 }
 
+// TickReport is the board's reply to a tick: the state of the buses once the
+// control word has settled. Firmware that also supports 'x' appends the
+// register latches.
+type TickReport struct {
+	Status    uint8
+	Address   uint16
+	Data      uint8
+	Error     uint8
+	Registers []byte
+}
+
+// Tick sets the control lines and, once the board reports that they have
+// settled, returns the status, address and data bus in a single reply. Replaces
+// SetLines, a fixed delay, ReadAddress, ReadData and ReadRegisters on firmware
+// that supports it. Such firmware also follows each clock edge event with the
+// status, so that a phase takes a single exchange (see EdgeStatus).
+func (s *Serial) Tick(data uint64, breakpoint bool) (TickReport, bool) {
+	if !s.connected {
+		return TickReport{}, false
+	}
+
+	if breakpoint {
+		data = data ^ instructionSet.CL_PAUS
+	}
+	bs := append(Tick, uint8(data >> 40), uint8(data >> 32), uint8(data >> 24), uint8(data >> 16), uint8(data >> 8), uint8(data))
+	rs, ok := s.exchange("tick report", bs, s.tick)
+	if !ok {
+		return TickReport{}, false
+	}
+	report := TickReport{Status: rs[0], Address: binary.LittleEndian.Uint16(rs[1:3]), Data: rs[3], Error: rs[4]}
+	if len(rs) > 5 {
+		report.Registers = rs[5:]
+	}
+	s.log.Tracef("Tick report: status %s, address %s, data %s", display.BinData(report.Status), display.HexAddress(report.Address), display.HexData(report.Data))
+	return report, true
+}
+
+// Host clock operations, sent with a 32-bit little endian argument
+const (
+	ClockPause  = 'p' // stop the clock
+	ClockPhase  = 'h' // advance the given number of phases
+	ClockCycles = 'c' // advance the given number of full cycles
+	ClockRun    = 'z' // run continuously at the given frequency in Hz
+)
+
+// Clock takes control of the board's clock
+func (s *Serial) Clock(op byte, arg uint32) bool {
+	if !s.connected {
+		return false
+	}
+	bs := append(ClockCtrl, op, uint8(arg), uint8(arg >> 8), uint8(arg >> 16), uint8(arg >> 24))
+	_, ok := s.exchange("clock acknowledgement", bs, s.ack)
+	return ok
+}
+
+// Interrupt asserts or releases the IRQ ('i'), NMI ('n') or RESET ('r') line
+func (s *Serial) Interrupt(line byte, asserted bool) bool {
+	if !s.connected {
+		return false
+	}
+	state := uint8(0)
+	if asserted {
+		state = 1
+	}
+	_, ok := s.exchange("interrupt acknowledgement", append(Interrupt, line, state), s.ack)
+	return ok
+}
+
 func (s *Serial) portMonitor(wg *sync.WaitGroup) {
 	wg.Add(1)
 	defer func() {
@@ -259,23 +321,31 @@ func (s *Serial) portMonitor(wg *sync.WaitGroup) {
 	}()
 
 	var err error
+	polls := 0
 	tick := time.NewTicker(200 * time.Millisecond)
 	for !s.terminated {
 		select {
 		case <- tick.C:
-			if s.port == nil {
-				if s.port, err = srl.Open(config.CLIConfig.Serial.PortName, s.mode); err != nil {
+			if s.port != nil {
+				continue
+			}
+			// A glob in the port name is expanded about once a second while disconnected
+			if polls++; s.portName == "" || polls % 5 == 0 {
+				s.portName = resolvePortName()
+			}
+			if s.refused != s.portName {
+				if s.port, err = srl.Open(s.portName, s.mode); err != nil {
 					s.port = nil
 					if s.connected {
 						s.connected = false
 						s.connStatus(false)
 					}
 				} else {
-					s.log.Infof("Opened port %s", config.CLIConfig.Serial.PortName)
-					if !s.connected {
-						s.connStatus(true)
-						s.connected = true
-					}
+					s.log.Infof("Opened port %s", s.portName)
+					s.firmware = nil
+					s.setFramed(config.CLIConfig.Serial.Framed)
+					s.resetStats()
+					go s.handshake()
 					s.readPort()
 				}
 			}
@@ -295,13 +365,19 @@ func (s *Serial) readPort() {
 		if n, err := s.port.Read(bs); err != nil {
 			s.port.Close()
 			s.port = nil
-			s.log.Infof("Lost port %s", config.CLIConfig.Serial.PortName)
+			s.log.Infof("Lost port %s", s.portName)
 			return
 		} else {
+			s.framing.Lock()
 			for i := 0; i < n; i++ {
-				s.buffer <- bs[i]
+				if s.framed {
+					s.deframe(bs[i])
+				} else {
+					s.buffer <- inbound{b: bs[i]}
+				}
 				bs[i] = 0
 			}
+			s.framing.Unlock()
 			//s.log.Warnf("Unexpected number of bytes received: Wanted 1, Received %d", n)
 		}
 	}
@@ -313,31 +389,46 @@ func (s *Serial) driver(wg *sync.WaitGroup) {
 		fmt.Println("Driver Done")
 		wg.Done()
 	}()
-	b, ok := byte(0), true
 	for {
-		if b, ok = <- s.buffer; !ok {
+		in, ok := <- s.buffer
+		if !ok {
 			break
 		}
+		b := in.b
 		s.log.Tracef("Inbound data: %s", string(b))
+		s.inbound = []byte{b}
 		switch b {
 		case 'a':
-			s.address <- []byte{<-s.buffer, <-s.buffer}
+			s.forward("address", s.address, in.seq, 2)
 		case 'd':
-			s.data <- <-s.buffer  // data
-			s.data <- <-s.buffer  // error code
-			s.log.Tracef("Forwarding 'd' data complete")
+			s.forward("data", s.data, in.seq, 2) // data, error code
 		case 'D':
-			s.data <- <-s.buffer  // error code
-			s.log.Tracef("Forwarding 'D' data complete")
+			s.forward("data acknowledgement", s.data, in.seq, 1) // error code
 		case 'o':
-			s.opCode <- <-s.buffer
-			s.log.Tracef("Forwarding 'o' data complete")
+			s.forward("OpCode", s.opCode, in.seq, 1)
 		case 's':
-			s.status <- <-s.buffer
-			s.log.Tracef("Forwarding 's' data complete")
+			s.forward("status", s.status, in.seq, 1)
+		case 'h':
+			bs := []byte{s.next(), s.next(), s.next(), s.next()}
+			for i := 0; i < int(bs[3]); i++ {
+				bs = append(bs, s.next())
+			}
+			s.deliver("hello", s.hello, reply{seq: in.seq, bs: bs})
+		case 'T':
+			size := 5
+			if s.Supports('x') {
+				size += status.RegCount
+			}
+			s.forward("tick report", s.tick, in.seq, size)
+		case 'k':
+			s.deliver("acknowledgement", s.ack, reply{seq: in.seq})
+		case 'x':
+			s.forward("registers", s.registers, in.seq, status.RegCount)
 		case 'c':
+			s.edge()
 			s.clock.ClockLow()
 		case 'C':
+			s.edge()
 			s.clock.ClockHigh()
 		case 'i':
 			s.irq.IrqLow()
@@ -354,9 +445,49 @@ func (s *Serial) driver(wg *sync.WaitGroup) {
 		default:
 			s.log.Warnf("Unknown byte: %v", display.HexData(b))
 		}
+		s.traffic.received(s.inbound)
 	}
 	s.log.Warn("Stopped receiving")
 }
+
+// edge records the status that firmware with the tick command sends after a clock edge
+func (s *Serial) edge() {
+	if s.Supports('T') {
+		atomic.StoreInt32(&s.edgeStatus, int32(s.next()))
+	}
+}
+
+// EdgeStatus returns, and clears, the status sent with the last clock edge. The
+// tick started by that edge uses it in place of ReadStatus.
+func (s *Serial) EdgeStatus() (uint8, bool) {
+	st := atomic.SwapInt32(&s.edgeStatus, -1)
+	return uint8(st), st >= 0
+}
+
+// next reads the following byte of an inbound message, keeping it for the traffic inspector
+func (s *Serial) next() byte {
+	in := <-s.buffer
+	s.inbound = append(s.inbound, in.b)
+	return in.b
+}
+
+// forward reads the remaining bytes of a reply and hands them to whoever awaits it
+func (s *Serial) forward(name string, replies chan reply, seq uint8, size int) {
+	bs := make([]byte, size)
+	for i := range bs {
+		bs[i] = s.next()
+	}
+	s.deliver(name, replies, reply{seq: seq, bs: bs})
+}
+
+// deliver never blocks the driver: a reply nobody is waiting for is discarded
+func (s *Serial) deliver(name string, replies chan reply, r reply) {
+	select {
+	case replies <- r:
+	default:
+		s.log.Debugf("Late %s discarded", name)
+	}
+}
 func (s *Serial) ResetChannels() {
 	for {
 		select {
@@ -364,6 +495,11 @@ func (s *Serial) ResetChannels() {
 		case <-s.address:
 		case <-s.opCode:
 		case <-s.data:
+		case <-s.status:
+		case <-s.registers:
+		case <-s.ack:
+		case <-s.tick:
+		case <-s.hello:
 		default:
 			if s.port != nil {
 				s.port.Close()
@@ -377,28 +513,100 @@ func (s *Serial) Draw(t *display.Terminal, connected bool, initialize bool) {
 	if initialize {
 		t.Cls()
 	}
-	ports, err := srl.GetPortsList()
-	if err != nil {
-		s.log.Errorf("%v", err)
-		return
+	t.PrintAtf(1,1, "%sSerial Ports%s", common.Yellow, common.Reset)
+	switch {
+	case s.firmware != nil && s.connected:
+		t.PrintAtf(20, 1, "%s%s: %s%s", common.White, s.portName, s.firmware, common.Reset)
+		if !s.firmware.Legacy {
+			t.PrintAtf(20, 2, "%sCommands: %s%s", common.White, strings.Join(strings.Split(string(s.firmware.Commands), ""), " "), common.Reset)
+		}
+		if s.isFramed() {
+			st := s.Stats()
+			t.PrintAtf(20, 3, "%sFramed: %d frame(s), %d CRC error(s), %d byte(s) dropped, %d stale%s", common.White, st.Frames, st.CrcErrors, st.Dropped, st.Stale, common.Reset)
+			t.PrintAtf(20, 4, "%s        %d timeout(s), %d retransmit(s)%s", common.White, st.Timeouts, st.Retransmits, common.Reset)
+		} else {
+			t.PrintAtf(20, 3, "%sRaw protocol, %d timeout(s)%s", common.White, s.Stats().Timeouts, common.Reset)
+		}
+	case s.refused != "":
+		t.PrintAtf(20, 1, "%s%s refused: incompatible firmware%s", common.Red, s.refused, common.Reset)
+	default:
+		t.PrintAtf(20, 1, "%sNot connected (%s)%s", common.Grey, config.CLIConfig.Serial.PortName, common.Reset)
+	}
+
+	if s.cursor >= len(s.ports) {
+		s.cursor = len(s.ports) - 1
 	}
-	if len(ports) == 0 {
-		s.log.Errorf("%v", err)
-		return
+	if s.cursor < 0 {
+		s.cursor = 0
 	}
-	t.PrintAtf(1,1, "%sSerial Ports%s", common.Yellow, common.Reset)
-	line := 2
-	for _, port := range ports {
-		if strings.HasPrefix(port, "/dev/cu") {
-			t.PrintAtf(4, line, "%s%v%s", common.Blue, port, common.Reset)
-			line ++
+	t.PrintAtf(1, 6, "%s   Port                  VID:PID    Product                  Link%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	for i, port := range s.ports {
+		if 7 + i >= t.Rows() {
+			break
 		}
+		marker := " "
+		if port.Name == s.portName {
+			marker = "*"
+		}
+		id := ""
+		if port.IsUSB {
+			id = port.VID + ":" + port.PID
+		}
+		line := fmt.Sprintf(" %s %-21s %-10s %-24s %s", marker, port.Name, id, port.Product, filepath.Base(port.Link))
+		if i == s.cursor {
+			line = common.Reversed + line + common.Reset
+		}
+		t.PrintAtf(1, 7 + i, "%s%s%s%s", common.Blue, line, common.Reset, display.ClearEnd)
+	}
+	if len(s.ports) == 0 {
+		t.PrintAtf(4, 7, "%sNo serial ports found%s", common.Grey, common.Reset)
+	}
+
+	help := s.keys.Help(keymap.Ports, "select", "refresh")
+	if s.refused != "" {
+		help += ", " + s.keys.Help(keymap.Ports, "retry")
 	}
-	t.PrintAtf(1, t.Rows(), "%sPress any key%s", common.Yellow, common.Reset)
+	t.PrintAtf(1, t.Rows(), "%s%s, any other key to exit%s%s", common.Yellow, help, common.Reset, display.ClearEnd)
 }
 func (s *Serial) Process(input common.Input) bool {
+	switch action := s.keys.Action(keymap.Ports, input); {
+	case action == "up":
+		if s.cursor > 0 {
+			s.cursor--
+		}
+		s.redraw(false)
+		return false
+	case action == "down":
+		if s.cursor < len(s.ports) - 1 {
+			s.cursor++
+		}
+		s.redraw(false)
+		return false
+	case action == "select":
+		if s.cursor < len(s.ports) {
+			s.SelectPort(s.ports[s.cursor].Name)
+		}
+		s.redraw(true)
+		return false
+	case action == "refresh":
+		s.refreshPorts()
+		s.redraw(true)
+		return false
+	case action == "retry" && s.refused != "":
+		s.log.Infof("Retrying %s", s.refused)
+		s.refused = ""
+	}
 	return true
 }
 func (s *Serial) PortViewer() common.UI {
+	s.refreshPorts()
 	return s
-}
\ No newline at end of file
+}
+
+// refreshPorts enumerates the ports when the viewer opens and when asked, rather than on every draw
+func (s *Serial) refreshPorts() {
+	var err error
+	if s.ports, err = ListPorts(); err != nil {
+		s.log.Errorf("Failed to list ports: %v", err)
+	}
+}
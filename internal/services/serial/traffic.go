@@ -0,0 +1,245 @@
+package serial
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"os"
+	"sync"
+	"time"
+)
+
+const maxTraffic = 2000
+
+// Message is one command sent to, or message received from, the board
+type Message struct {
+	Time     time.Time
+	Outbound bool
+	Bytes    []byte
+	Latency  time.Duration
+	decoded  string
+}
+
+// Traffic records every message exchanged with the board, for the traffic
+// inspector page and, when serial.traffic_log is set, a log file
+type Traffic struct {
+	messages []Message
+	pending  map[byte]time.Time
+	file     *os.File
+	clock    func() uint8
+	log      func(format string, args ...interface{})
+	redraw   func(bool)
+	keys     *keymap.Keymap
+	list     common.List
+	follow   bool
+	sync     sync.Mutex
+}
+
+func newTraffic(s *Serial) *Traffic {
+	t := &Traffic{
+		pending: map[byte]time.Time{},
+		clock:   s.clock.CurrentState,
+		log:     s.log.Warnf,
+		redraw:  s.redraw,
+		keys:    s.keys,
+		follow:  true,
+	}
+	if name := config.CLIConfig.Serial.TrafficLog; name != "" {
+		var err error
+		if t.file, err = os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			s.log.Warnf("Failed to open traffic log %s: %v", name, err)
+			t.file = nil
+		}
+	}
+	return t
+}
+
+// replyTag gives the tag of the message answering a command
+func replyTag(command byte) byte {
+	if command == 'L' || command == 'K' || command == 'Q' {
+		return 'k'
+	}
+	return command
+}
+
+func (t *Traffic) sent(cmd []byte) {
+	if len(cmd) < 2 {
+		return
+	}
+	now := time.Now()
+	t.sync.Lock()
+	t.pending[replyTag(cmd[1])] = now
+	t.sync.Unlock()
+	t.record(Message{Time: now, Outbound: true, Bytes: append([]byte(nil), cmd[1:]...)})
+}
+func (t *Traffic) received(bs []byte) {
+	now := time.Now()
+	m := Message{Time: now, Bytes: bs}
+	t.sync.Lock()
+	if sent, ok := t.pending[bs[0]]; ok {
+		m.Latency = now.Sub(sent)
+		delete(t.pending, bs[0])
+	}
+	t.sync.Unlock()
+	t.record(m)
+}
+func (t *Traffic) record(m Message) {
+	m.decoded = t.decode(m)
+	t.sync.Lock()
+	t.messages = append(t.messages, m)
+	if len(t.messages) > maxTraffic {
+		t.messages = t.messages[len(t.messages)-maxTraffic:]
+		if !t.follow && t.list.Offset > 0 {
+			t.list.Offset--
+		}
+	}
+	t.sync.Unlock()
+	if t.file != nil {
+		if _, err := fmt.Fprintln(t.file, t.format(m)); err != nil {
+			t.log("Failed to write traffic log: %v", err)
+			t.file.Close()
+			t.file = nil
+		}
+	}
+}
+
+func (t *Traffic) decode(m Message) string {
+	bs := m.Bytes
+	arg := func(n int) uint8 {
+		if n < len(bs) {
+			return bs[n]
+		}
+		return 0
+	}
+	word := func() uint64 {
+		w := uint64(0)
+		for i := 1; i <= 6; i++ {
+			w = w << 8 | uint64(arg(i))
+		}
+		return w
+	}
+	if m.Outbound {
+		switch bs[0] {
+		case 'o': return "Get opcode"
+		case 'a': return "Get address"
+		case 'd': return "Get data"
+		case 's': return "Get status"
+		case 'x': return "Get registers"
+		case 'h': return "Hello"
+		case 'D': return "Set data $" + display.HexData(arg(1))
+		case 'L': return "Set lines " + instructionSet.DescribeWord(t.clock(), word())
+		case 'T': return "Tick " + instructionSet.DescribeWord(t.clock(), word())
+		case 'Q': return fmt.Sprintf("Interrupt %q set to %d", arg(1), arg(2))
+		case 'K': return fmt.Sprintf("Clock %q %d", arg(1), binary.LittleEndian.Uint32([]byte{arg(2), arg(3), arg(4), arg(5)}))
+		}
+		return fmt.Sprintf("Command %q", bs[0])
+	}
+	switch bs[0] {
+	case 'a': return "Address $" + display.HexAddress(binary.LittleEndian.Uint16([]byte{arg(1), arg(2)}))
+	case 'd': return fmt.Sprintf("Data $%s, error %d", display.HexData(arg(1)), arg(2))
+	case 'D': return fmt.Sprintf("Data set, error %d", arg(1))
+	case 'o': return "Opcode $" + display.HexData(arg(1))
+	case 's': return "Status " + display.BinData(arg(1))
+	case 'k': return "Acknowledged"
+	case 'x': return fmt.Sprintf("A $%02X X $%02X Y $%02X SP $%02X PC $%02X%02X", arg(1), arg(2), arg(3), arg(4), arg(6), arg(5))
+	case 'h': return fmt.Sprintf("Firmware %d.%d, protocol %d", arg(1), arg(2), arg(3))
+	case 'T': return fmt.Sprintf("Tick status %s, address $%02X%02X, data $%02X, error %d", display.BinData(arg(1)), arg(3), arg(2), arg(4), arg(5))
+	case 'c': return "Clock low"
+	case 'C': return "Clock high"
+	case 'i': return "IRQ low"
+	case 'I': return "IRQ high"
+	case 'n': return "NMI low"
+	case 'N': return "NMI high"
+	case 'r': return "Reset low"
+	case 'R': return "Reset high"
+	}
+	return "Unknown byte"
+}
+func (t *Traffic) format(m Message) string {
+	direction := "<-"
+	if m.Outbound {
+		direction = "->"
+	}
+	latency := ""
+	if m.Latency > 0 {
+		latency = fmt.Sprintf(" (%.1fms)", float64(m.Latency.Microseconds()) / 1000)
+	}
+	return fmt.Sprintf("%s %s % -24X %s%s", m.Time.Format("15:04:05.000"), direction, m.Bytes, m.decoded, latency)
+}
+
+func (t *Traffic) Clear() {
+	t.sync.Lock()
+	t.messages = nil
+	t.list.Top()
+	t.follow = true
+	t.sync.Unlock()
+}
+
+func (t *Traffic) Draw(term *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		term.Cls()
+	}
+	term.HideCursor()
+	t.sync.Lock()
+	defer t.sync.Unlock()
+	if t.follow {
+		t.list.Offset = len(t.messages)
+	}
+	t.list.Layout(term.Rows() - 3, len(t.messages))
+
+	title := "Serial traffic"
+	if t.file != nil {
+		title += " (logging to " + t.file.Name() + ")"
+	}
+	term.PrintAtf(1, 1, "%s%s%s%s", common.Yellow, title, common.Reset, display.ClearEnd)
+	for row := 0; row < t.list.Rows; row++ {
+		line := ""
+		if i, ok := t.list.Item(row); ok {
+			m := t.messages[i]
+			colour := common.White
+			if m.Outbound {
+				colour = common.Cyan
+			}
+			line = colour + t.format(m) + common.Reset
+			if len(line) > term.Cols() + len(colour) + len(common.Reset) {
+				line = line[:term.Cols() + len(colour)] + common.Reset
+			}
+		}
+		term.PrintAtf(1, row+2, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(term, t.keys.Help(keymap.Traffic, "follow", "clear") + ", any other key to exit")
+}
+func (t *Traffic) Process(input common.Input) bool {
+	t.sync.Lock()
+	switch action := t.keys.Action(keymap.Traffic, input); action {
+	case "up", "down", "page_up", "page_down":
+		t.list.Move(action)
+		if action == "up" || action == "page_up" {
+			t.follow = false
+		}
+	case "follow":
+		t.follow = true
+	case "clear":
+		t.sync.Unlock()
+		t.Clear()
+		t.redraw(true)
+		return false
+	default:
+		t.sync.Unlock()
+		return true
+	}
+	if t.list.AtEnd() {
+		t.follow = true
+	}
+	t.sync.Unlock()
+	t.redraw(false)
+	return false
+}
+
+func (s *Serial) TrafficViewer() common.UI {
+	return s.traffic
+}
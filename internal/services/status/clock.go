@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"time"
 )
 
 const (
@@ -11,10 +12,26 @@ const (
 	clockLow  = common.Cyan
 )
 
+// Clock modes. The board's own oscillator drives the clock until the host
+// takes control with a step, run or pause command.
+const (
+	ClockBoard = iota
+	ClockPaused
+	ClockStepping
+	ClockRunning
+)
+
 type Clock struct {
-	state  uint8
-	tick   func(bool)
-	log    *logging.Log
+	state     uint8
+	tick      func(bool)
+	log       *logging.Log
+	mode      int
+	hz        uint32
+	remaining uint32
+	edges     int
+	since     time.Time
+	lastEdge  time.Time
+	measured  float64
 }
 func NewClock(log *logging.Log, tick func(bool)) *Clock {
 	return &Clock{
@@ -26,13 +43,43 @@ func NewClock(log *logging.Log, tick func(bool)) *Clock {
 func (c *Clock) ClockHigh() {
 	phaseChange := c.state == 0
 	c.state = 1
+	c.measure()
 	c.tick(phaseChange)
 }
 func (c *Clock) ClockLow() {
 	phaseChange := c.state == 1
 	c.state = 0
+	c.measure()
+	if phaseChange && c.remaining > 0 {
+		if c.remaining--; c.remaining == 0 {
+			c.mode = ClockPaused
+		}
+	}
 	c.tick(phaseChange)
 }
+
+// measure updates the effective speed, in full cycles per second, about once a second
+func (c *Clock) measure() {
+	now := time.Now()
+	c.lastEdge = now
+	c.edges++
+	if elapsed := now.Sub(c.since); elapsed >= time.Second {
+		c.measured = float64(c.edges) / 2 / elapsed.Seconds()
+		c.edges = 0
+		c.since = now
+	}
+}
+
+// SetMode records what the host last asked the clock to do. A non-zero count
+// of cycles returns the clock to paused once that many cycles have been seen.
+func (c *Clock) SetMode(mode int, hz uint32, cycles uint32) {
+	c.mode = mode
+	c.hz = hz
+	c.remaining = cycles
+}
+func (c *Clock) Mode() int {
+	return c.mode
+}
 func (c *Clock) CurrentState() uint8 {
 	return c.state
 }
@@ -44,3 +91,36 @@ func (c *Clock) Block() string {
 	}
 	return fmt.Sprintf("%sΦ%d%s", str, c.state + 1, common.Reset)
 }
+
+// ModeBlock describes the clock mode and the measured speed for the clock panel
+func (c *Clock) ModeBlock() string {
+	speed := c.measured
+	if time.Since(c.lastEdge) > 2 * time.Second {
+		speed = 0
+	}
+	mode := ""
+	switch c.mode {
+	case ClockBoard:
+		mode = "board"
+	case ClockPaused:
+		mode = "paused"
+	case ClockStepping:
+		mode = "step"
+	case ClockRunning:
+		if c.remaining > 0 {
+			mode = fmt.Sprintf("run %d", c.remaining)
+		} else {
+			mode = fmt.Sprintf("run %dHz", c.hz)
+		}
+	}
+	return fmt.Sprintf("%s%-9s%s%6s%s", common.White, mode, common.Grey, formatHz(speed), common.Reset)
+}
+func formatHz(hz float64) string {
+	switch {
+	case hz >= 1000:
+		return fmt.Sprintf("%.1fk", hz / 1000)
+	case hz >= 10:
+		return fmt.Sprintf("%.0fHz", hz)
+	}
+	return fmt.Sprintf("%.1fHz", hz)
+}
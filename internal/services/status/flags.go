@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
 	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"strings"
 )
 
 var (
@@ -20,11 +22,13 @@ type Flags struct {
 	terminal     *display.Terminal
 	redraw       func(bool)
 	cursor       common.Coord
+	keys         *keymap.Keymap
 	Ignore       bool
 }
-func NewFlags(log *logging.Log, terminal *display.Terminal, redraw func(bool)) *Flags {
+func NewFlags(log *logging.Log, terminal *display.Terminal, redraw func(bool), keys *keymap.Keymap) *Flags {
 	return &Flags{
 		log:      log,
+		keys:     keys,
 		Ignore:   false,
 		redraw:   redraw,
 		terminal: terminal,
@@ -43,12 +47,28 @@ func (f *Flags) SyncFlags() {
 	f.log.Info("Set Developer flags to current flags")
 	f.devFlags = f.currentFlags
 }
+// Flag reports whether the named status flag (N, V, I, Z or C) is set. B and D
+// aren't latched in the status byte, so they aren't found.
+func (f *Flags) Flag(name string) (bool, bool) {
+	for n, label := range labels {
+		if strings.EqualFold(label, name) && bit[n] > 0 {
+			return f.flags & bit[n] > 0, true
+		}
+	}
+	return false, false
+}
+func (f *Flags) Status() uint8 {
+	return f.flags
+}
 func (f *Flags) CurrentFlags() uint8 {
 	return f.currentFlags
 }
 func (f *Flags) DevFlags() uint8 {
 	return f.devFlags
 }
+func (f *Flags) SetDevFlags(flags uint8) {
+	f.devFlags = flags & 15
+}
 func (f *Flags) FlagsBlock() string {
 	str := ""
 	lastColour := ""
@@ -98,6 +118,10 @@ func (f *Flags) Right(n int) {
 		f.terminal.Bell()
 	}
 }
+// CursorBit is the flags index bit under the flags editor cursor
+func (f *Flags) CursorBit() uint8 {
+	return 1 << (3 - f.cursor.X)
+}
 func (f *Flags) PositionCursor() {
 	f.terminal.At(f.cursor.X * 2 + 17, 20)
 }
@@ -113,30 +137,19 @@ func (f *Flags) DevBlock() string {
 	return fmt.Sprintf(" %sN %sV %sZ %sC -> %s%02d ", c1, c2, c3, c4, common.BrightBlue, f.devFlags)
 }
 func (f *Flags) KeyIntercept(input common.Input) bool {
-	if input.KeyCode != 0 {
-		switch input.KeyCode {
-		case display.CursorUp:
-			f.Up()
-		case display.CursorDown:
-			f.Down()
-		case display.CursorLeft:
-			f.Left(1)
-		case display.CursorRight:
-			f.Right(1)
-		default:
-			return false
-		}
-	} else if input.Ascii > 0 {
-		switch input.Ascii {
-		case '1':
-			f.Up()
-		case '0':
-			f.Down()
-		case ' ', 13:
-			f.Toggle()
-		default:
-			return false
-		}
+	switch f.keys.Action(keymap.Flags, input) {
+	case "set":
+		f.Up()
+	case "clear":
+		f.Down()
+	case "left":
+		f.Left(1)
+	case "right":
+		f.Right(1)
+	case "toggle":
+		f.Toggle()
+	default:
+		return false
 	}
 	return true
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package status
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"strings"
+)
+
+const (
+	RegA = iota
+	RegX
+	RegY
+	RegSP
+	RegPCL
+	RegPCH
+	RegCount
+)
+
+var registerNames = []string{"A", "X", "Y", "SP"}
+
+// Registers holds the register latches last read from the board, along with
+// their values at the end of the previous step so that the changes made by the
+// current step can be highlighted. There is no simulator to fall back on, so
+// without a board, or with firmware lacking the 'x' command, the panel shows --.
+type Registers struct {
+	values   [RegCount]uint8
+	previous [RegCount]uint8
+	valid    bool
+	log      *logging.Log
+}
+func NewRegisters(log *logging.Log) *Registers {
+	return &Registers{
+		log: log,
+	}
+}
+
+func (r *Registers) SetRegisters(values []uint8) {
+	if len(values) != RegCount {
+		r.log.Warnf("Expected %d register bytes, received %d", RegCount, len(values))
+		return
+	}
+	copy(r.values[:], values)
+	if !r.valid {
+		r.previous = r.values
+		r.valid = true
+	}
+}
+
+// Step is called as each step starts, before its registers are read, so that the
+// values shown as changed are those written since the previous step
+func (r *Registers) Step() {
+	r.previous = r.values
+}
+func (r *Registers) Invalidate() {
+	r.valid = false
+}
+func (r *Registers) Valid() bool {
+	return r.valid
+}
+func (r *Registers) PC() uint16 {
+	return uint16(r.values[RegPCH]) << 8 | uint16(r.values[RegPCL])
+}
+
+// Register looks up a register by name (A, X, Y, SP or PC), case-insensitively
+func (r *Registers) Register(name string) (uint16, bool) {
+	if !r.valid {
+		return 0, false
+	}
+	name = strings.ToUpper(name)
+	if name == "PC" {
+		return r.PC(), true
+	}
+	for i, reg := range registerNames {
+		if reg == name {
+			return uint16(r.values[i]), true
+		}
+	}
+	return 0, false
+}
+
+func (r *Registers) RegistersBlock() string {
+	str := ""
+	for i, name := range registerNames {
+		str = fmt.Sprintf("%s%s%s %s ", str, common.Yellow, name, r.value(i))
+	}
+	pc := fmt.Sprintf("%s--%s--", off, off)
+	if r.valid {
+		pc = r.value(RegPCH) + r.value(RegPCL)
+	}
+	return fmt.Sprintf("%s%sPC %s%s", str, common.Yellow, pc, common.Reset)
+}
+func (r *Registers) value(index int) string {
+	switch {
+	case !r.valid:
+		return off + "--"
+	case r.values[index] != r.previous[index]:
+		return fmt.Sprintf("%s%02X", common.BrightYellow, r.values[index])
+	}
+	return fmt.Sprintf("%s%02X", common.White, r.values[index])
+}
@@ -0,0 +1,73 @@
+package trace
+
+import (
+	"sync"
+)
+
+// depth is how many phases the recorder keeps
+const depth = 8192
+
+// Sample is the state of the board during one clock phase. Cycle is the cycle the
+// phase belongs to, and Static samples come from the microcode alone, so their
+// buses are unknown.
+type Sample struct {
+	Cycle   uint64
+	OpCode  uint8
+	Name    string
+	Flags   uint8
+	Step    uint8
+	Phase   uint8
+	Lines   uint64
+	Address uint16
+	Data    uint8
+	HasData bool
+	Static  bool
+}
+
+// Recorder keeps the most recent phases sent to the board
+type Recorder struct {
+	samples []Sample
+	next    int
+	full    bool
+	sync    sync.Mutex
+}
+
+func New() *Recorder {
+	return &Recorder{
+		samples: make([]Sample, depth),
+	}
+}
+
+func (r *Recorder) Record(s Sample) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+	r.samples[r.next] = s
+	if r.next++; r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+func (r *Recorder) Clear() {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+	r.next = 0
+	r.full = false
+}
+func (r *Recorder) Len() int {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+	if r.full {
+		return len(r.samples)
+	}
+	return r.next
+}
+
+// Samples returns a copy of the recording, oldest first
+func (r *Recorder) Samples() []Sample {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+	if !r.full {
+		return append([]Sample(nil), r.samples[:r.next]...)
+	}
+	return append(append([]Sample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
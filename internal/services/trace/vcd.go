@@ -0,0 +1,154 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"io"
+	"os"
+	"time"
+)
+
+// signal is one variable in a value change dump
+type signal struct {
+	id    string
+	name  string
+	width int
+	value func(s Sample) (uint64, bool)
+}
+
+// vcdId encodes a signal index as a VCD identifier from the printable ASCII range
+func vcdId(index int) string {
+	id := ""
+	for {
+		id += string(rune('!' + index % 94))
+		if index /= 94; index == 0 {
+			return id
+		}
+	}
+}
+
+func signals() []signal {
+	var list []signal
+	add := func(name string, width int, value func(s Sample) (uint64, bool)) {
+		list = append(list, signal{id: vcdId(len(list)), name: name, width: width, value: value})
+	}
+	for _, name := range instructionSet.Mnemonics() {
+		bit, _ := instructionSet.LineBit(name)
+		add("CL_" + name, 1, func(s Sample) (uint64, bool) {
+			if s.Lines & bit != 0 {
+				return 1, true
+			}
+			return 0, true
+		})
+	}
+	add("address", 16, func(s Sample) (uint64, bool) { return uint64(s.Address), !s.Static })
+	add("data", 8, func(s Sample) (uint64, bool) { return uint64(s.Data), s.HasData })
+	add("opcode", 8, func(s Sample) (uint64, bool) { return uint64(s.OpCode), true })
+	add("step", 3, func(s Sample) (uint64, bool) { return uint64(s.Step), true })
+	add("phase", 1, func(s Sample) (uint64, bool) { return uint64(s.Phase), true })
+	add("flags", 4, func(s Sample) (uint64, bool) { return uint64(s.Flags), true })
+	return list
+}
+
+func (sig *signal) format(s Sample) string {
+	value, known := sig.value(s)
+	if sig.width == 1 {
+		if !known {
+			return "x" + sig.id
+		}
+		return fmt.Sprintf("%d%s", value, sig.id)
+	}
+	if !known {
+		return "bx " + sig.id
+	}
+	return fmt.Sprintf("b%b %s", value, sig.id)
+}
+
+// OpCodeSamples lays out the static microcode of an opcode, for the steps its flags
+// variant runs, as a run of phases. The buses aren't known, so they are dumped as x.
+func OpCodeSamples(oc *instructionSet.OpCode, flags uint8) []Sample {
+	var samples []Sample
+	for step, steps := uint8(0), oc.StepCount(flags); step < steps; step++ {
+		for phase := uint8(0); phase < 2; phase++ {
+			samples = append(samples, Sample{
+				Cycle:  uint64(step),
+				OpCode: oc.OpCode,
+				Name:   oc.Name,
+				Flags:  flags,
+				Step:   step,
+				Phase:  phase,
+				Lines:  oc.Lines[flags][step][phase],
+				Static: true,
+			})
+		}
+	}
+	return samples
+}
+
+// WriteVCD writes the samples as a value change dump, timed from their cycle and
+// phase with each phase lasting phaseNs nanoseconds, so phases that weren't
+// recorded show as gaps. Time never runs backwards, even across a board reset.
+// Control lines are dumped at their electrical level, so active low lines idle high.
+func WriteVCD(w io.Writer, samples []Sample, phaseNs uint64, comment string) error {
+	b := bufio.NewWriter(w)
+	list := signals()
+	fmt.Fprintf(b, "$date %s $end\n", time.Now().Format(time.RFC1123))
+	fmt.Fprintf(b, "$version logic-ctl $end\n")
+	fmt.Fprintf(b, "$comment %s $end\n", comment)
+	fmt.Fprintf(b, "$timescale 1ns $end\n")
+	fmt.Fprintf(b, "$scope module cpu $end\n")
+	for _, sig := range list {
+		if sig.width == 1 {
+			fmt.Fprintf(b, "$var wire 1 %s %s $end\n", sig.id, sig.name)
+		} else {
+			fmt.Fprintf(b, "$var wire %d %s %s [%d:0] $end\n", sig.width, sig.id, sig.name, sig.width - 1)
+		}
+	}
+	fmt.Fprintf(b, "$upscope $end\n$enddefinitions $end\n")
+
+	last := make([]string, len(list))
+	// offset maps a sample's phase number to its time slot
+	var offset, slot int64
+	for i, s := range samples {
+		phase := int64(s.Cycle * 2) + int64(s.Phase)
+		if i == 0 {
+			offset = -phase
+		} else if phase + offset <= slot {
+			// After a reset, or a repeated phase, carry on from the previous sample
+			offset = slot + 1 - phase
+		}
+		slot = phase + offset
+		fmt.Fprintf(b, "#%d\n", uint64(slot) * phaseNs)
+		if i == 0 {
+			fmt.Fprintf(b, "$dumpvars\n")
+		}
+		for j := range list {
+			if value := list[j].format(s); value != last[j] {
+				fmt.Fprintln(b, value)
+				last[j] = value
+			}
+		}
+		if i == 0 {
+			fmt.Fprintf(b, "$end\n")
+		}
+	}
+	if len(samples) > 0 {
+		fmt.Fprintf(b, "#%d\n", uint64(slot + 1) * phaseNs)
+	}
+	return b.Flush()
+}
+
+// ExportVCD writes the samples to a .vcd file
+func ExportVCD(filename string, samples []Sample, phaseNs uint64, comment string) (err error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return WriteVCD(f, samples, phaseNs, comment)
+}
@@ -0,0 +1,79 @@
+package undo
+
+import (
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"sync"
+)
+
+const maxActions = 500
+
+// Action is a reversible edit. Undo and Redo restore the state before and
+// after the edit respectively; Description is logged when either is applied.
+type Action struct {
+	Description string
+	Undo        func()
+	Redo        func()
+}
+
+// Stack is the undo/redo history shared by the memory, control line and bus editors
+type Stack struct {
+	done   []Action
+	undone []Action
+	log    *logging.Log
+	sync   sync.Mutex
+}
+
+func New(log *logging.Log) *Stack {
+	return &Stack{
+		log: log,
+	}
+}
+
+// Push records an edit that has already been applied, discarding anything that could be redone
+func (s *Stack) Push(action Action) {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+	s.done = append(s.done, action)
+	if len(s.done) > maxActions {
+		s.done = s.done[1:]
+	}
+	s.undone = nil
+}
+// Clear forgets every edit, for when the memory or microcode they were made to is replaced
+func (s *Stack) Clear() {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+	s.done, s.undone = nil, nil
+}
+func (s *Stack) Undo() bool {
+	s.sync.Lock()
+	if len(s.done) == 0 {
+		s.sync.Unlock()
+		s.log.Info("Nothing to undo")
+		return false
+	}
+	action := s.done[len(s.done)-1]
+	s.done = s.done[:len(s.done)-1]
+	s.undone = append(s.undone, action)
+	s.sync.Unlock()
+
+	action.Undo()
+	s.log.Infof("Undo: %s", action.Description)
+	return true
+}
+func (s *Stack) Redo() bool {
+	s.sync.Lock()
+	if len(s.undone) == 0 {
+		s.sync.Unlock()
+		s.log.Info("Nothing to redo")
+		return false
+	}
+	action := s.undone[len(s.undone)-1]
+	s.undone = s.undone[:len(s.undone)-1]
+	s.done = append(s.done, action)
+	s.sync.Unlock()
+
+	action.Redo()
+	s.log.Infof("Redo: %s", action.Description)
+	return true
+}
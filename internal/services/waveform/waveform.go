@@ -0,0 +1,366 @@
+package waveform
+
+import (
+	"fmt"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/config"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/common"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/display"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/instructionSet"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/keymap"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/logging"
+	"github.td.teradata.com/sandbox/logic-ctl/internal/services/trace"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	nameWidth = 7
+	maxZoom   = 8
+	high      = '▔'
+	low       = '▁'
+	rising    = '╱'
+	falling   = '╲'
+)
+
+var defaultLines = []string{"CTMR", "PCIN", "DBRW", "ALLD", "AHLD", "PCLL", "PCLH", "AULA", "AULB", "SBLA"}
+
+// column is one phase of the waveform
+type column struct {
+	word  uint64
+	label string
+}
+
+// Waveform draws selected control lines as logic analyser traces, either across
+// the steps of the current opcode or across the most recently recorded phases
+type Waveform struct {
+	log      *logging.Log
+	redraw   func(bool)
+	current  func() (*instructionSet.OpCode, uint8)
+	recorder *trace.Recorder
+	hz       func() uint32
+	keys     *keymap.Keymap
+	lines    []uint64
+	trace    bool
+	asserted bool
+	zoom     int
+	offset   int
+	row      int
+	picking  bool
+	pick     int
+	visible  int
+}
+
+// New takes a function returning the current opcode and flags, the recorder of
+// past phases, and a function returning the clock speed used to time exports
+func New(log *logging.Log, redraw func(bool), current func() (*instructionSet.OpCode, uint8), recorder *trace.Recorder, hz func() uint32, keys *keymap.Keymap) *Waveform {
+	w := &Waveform{
+		log:      log,
+		redraw:   redraw,
+		current:  current,
+		recorder: recorder,
+		hz:       hz,
+		keys:     keys,
+		asserted: true,
+		zoom:     3,
+	}
+	for _, name := range defaultLines {
+		if bit, ok := instructionSet.LineBit(name); ok {
+			w.lines = append(w.lines, bit)
+		}
+	}
+	return w
+}
+
+func (w *Waveform) columns() []column {
+	var columns []column
+	if w.trace {
+		for _, s := range w.recorder.Samples() {
+			label := ""
+			if s.Step == 0 && s.Phase == instructionSet.PHI1 {
+				label = s.Name
+			}
+			columns = append(columns, column{word: s.Lines, label: label})
+		}
+		return columns
+	}
+	oc, flags := w.current()
+	if oc == nil {
+		return nil
+	}
+	for step := uint8(0); step < oc.Steps; step++ {
+		for phase := uint8(0); phase < 2; phase++ {
+			label := ""
+			if phase == instructionSet.PHI1 {
+				label = fmt.Sprintf("%d", step)
+			}
+			columns = append(columns, column{word: oc.Lines[flags][step][phase], label: label})
+		}
+	}
+	return columns
+}
+
+// window picks the columns that fit on screen. The trace view counts its offset back from the latest phase.
+func (w *Waveform) window(columns []column, width int) []column {
+	w.visible = width / w.zoom
+	if w.offset > len(columns) - w.visible {
+		w.offset = len(columns) - w.visible
+	}
+	if w.offset < 0 {
+		w.offset = 0
+	}
+	start := w.offset
+	if w.trace {
+		start = len(columns) - w.visible - w.offset
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + w.visible
+	if end > len(columns) {
+		end = len(columns)
+	}
+	return columns[start:end]
+}
+
+func (w *Waveform) level(word uint64, bit uint64) bool {
+	level := word & bit != 0
+	if w.asserted && instructionSet.ActiveLow(bit) {
+		level = !level
+	}
+	return level
+}
+func (w *Waveform) wave(columns []column, bit uint64) string {
+	var sb strings.Builder
+	for i, c := range columns {
+		level := w.level(c.word, bit)
+		for z := 0; z < w.zoom; z++ {
+			switch {
+			case z == 0 && i > 0 && level != w.level(columns[i-1].word, bit):
+				if level {
+					sb.WriteRune(rising)
+				} else {
+					sb.WriteRune(falling)
+				}
+			case level:
+				sb.WriteRune(high)
+			default:
+				sb.WriteRune(low)
+			}
+		}
+	}
+	return sb.String()
+}
+func (w *Waveform) ruler(columns []column) string {
+	ruler := []rune(strings.Repeat(" ", len(columns) * w.zoom))
+	free := 0
+	for i, c := range columns {
+		if c.label == "" || i * w.zoom < free {
+			continue
+		}
+		for j, r := range c.label {
+			if i * w.zoom + j < len(ruler) {
+				ruler[i * w.zoom + j] = r
+			}
+		}
+		free = i * w.zoom + len(c.label) + 1
+	}
+	return string(ruler)
+}
+
+func lineName(bit uint64) string {
+	for _, name := range instructionSet.Mnemonics() {
+		if b, _ := instructionSet.LineBit(name); b == bit {
+			return name
+		}
+	}
+	return "?"
+}
+
+func (w *Waveform) Draw(t *display.Terminal, connected bool, initialize bool) {
+	if initialize {
+		t.Cls()
+	}
+	t.HideCursor()
+	if w.picking {
+		w.drawPicker(t)
+		return
+	}
+
+	source := "opcode"
+	if oc, _ := w.current(); !w.trace && oc != nil {
+		source = fmt.Sprintf("opcode %s %s", display.HexData(oc.OpCode), oc.Name)
+	} else if w.trace {
+		source = fmt.Sprintf("trace of %d phase(s)", w.recorder.Len())
+	}
+	levels := "levels"
+	if w.asserted {
+		levels = "asserted"
+	}
+	columns := w.window(w.columns(), t.Cols() - nameWidth - 1)
+	t.PrintAtf(1, 1, "%sWaveform: %s, %s, zoom %d%s%s", common.Yellow, source, levels, w.zoom, common.Reset, display.ClearEnd)
+	t.PrintAtf(1, 2, "%s%*s%s%s%s", common.Grey, nameWidth, "", w.ruler(columns), common.Reset, display.ClearEnd)
+
+	rows := t.Rows() - 3
+	top := 0
+	if w.row >= rows {
+		top = w.row - rows + 1
+	}
+	for row := 0; row < rows; row++ {
+		line := ""
+		if i := top + row; i < len(w.lines) {
+			colour := common.White
+			if i == w.row {
+				colour = common.BrightYellow
+			}
+			line = fmt.Sprintf("%s%-*s%s%s", colour, nameWidth, lineName(w.lines[i]), common.BrightGreen, w.wave(columns, w.lines[i])) + common.Reset
+		}
+		t.PrintAtf(1, row + 3, "%s%s", line, display.ClearEnd)
+	}
+	common.Footer(t, w.keys.Help(keymap.Waveform, "left", "right", "zoom_in", "zoom_out", "pick_lines", "drop_line", "view", "asserted", "clear", "export") + ", any other key to exit")
+}
+func (w *Waveform) drawPicker(t *display.Terminal) {
+	t.PrintAtf(1, 1, "%sSelect control lines%s%s", common.Yellow, common.Reset, display.ClearEnd)
+	for i, name := range instructionSet.Mnemonics() {
+		bit, _ := instructionSet.LineBit(name)
+		mark, colour := " ", common.White
+		if w.selected(bit) >= 0 {
+			mark, colour = "*", common.BrightGreen
+		}
+		if i == w.pick {
+			colour = common.BGBlue + colour
+		}
+		t.PrintAtf(1 + (i % 8) * 8, 3 + i / 8, "%s%s%s%s ", colour, mark, name, common.Reset)
+	}
+	for row := 9; row < t.Rows(); row++ {
+		t.PrintAtf(1, row, display.ClearEnd)
+	}
+	common.Footer(t, w.keys.Help(keymap.LinePicker, "toggle", "done"))
+}
+func (w *Waveform) selected(bit uint64) int {
+	for i, b := range w.lines {
+		if b == bit {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *Waveform) Process(input common.Input) bool {
+	if w.picking {
+		w.processPicker(input)
+		w.redraw(true)
+		return false
+	}
+	switch w.keys.Action(keymap.Waveform, input) {
+	case "up":
+		if w.row > 0 {
+			w.row--
+		}
+	case "down":
+		if w.row < len(w.lines) - 1 {
+			w.row++
+		}
+	case "left":
+		w.scroll(-1)
+	case "right":
+		w.scroll(1)
+	case "page_up":
+		w.scroll(-w.visible)
+	case "page_down":
+		w.scroll(w.visible)
+	case "zoom_in":
+		if w.zoom < maxZoom {
+			w.zoom++
+		}
+	case "zoom_out":
+		if w.zoom > 1 {
+			w.zoom--
+		}
+	case "pick_lines":
+		w.picking = true
+	case "drop_line":
+		if len(w.lines) > 0 {
+			w.lines = append(w.lines[:w.row], w.lines[w.row+1:]...)
+			if w.row >= len(w.lines) && w.row > 0 {
+				w.row--
+			}
+		}
+	case "view":
+		w.trace = !w.trace
+		w.offset = 0
+	case "asserted":
+		w.asserted = !w.asserted
+	case "clear":
+		w.recorder.Clear()
+		w.log.Info("Trace cleared")
+	case "export":
+		w.export()
+	default:
+		return true
+	}
+	w.redraw(true)
+	return false
+}
+
+// scroll moves the view later in time, or earlier for a negative count
+func (w *Waveform) scroll(n int) {
+	if w.trace {
+		w.offset -= n
+	} else {
+		w.offset += n
+	}
+}
+func (w *Waveform) processPicker(input common.Input) {
+	count := len(instructionSet.Mnemonics())
+	switch action := w.keys.Action(keymap.LinePicker, input); {
+	case action == "up" && w.pick >= 8:
+		w.pick -= 8
+	case action == "down" && w.pick + 8 < count:
+		w.pick += 8
+	case action == "left" && w.pick > 0:
+		w.pick--
+	case action == "right" && w.pick < count - 1:
+		w.pick++
+	case action == "toggle":
+		bit, _ := instructionSet.LineBit(instructionSet.Mnemonics()[w.pick])
+		if i := w.selected(bit); i >= 0 {
+			w.lines = append(w.lines[:i], w.lines[i+1:]...)
+		} else {
+			w.lines = append(w.lines, bit)
+		}
+		if w.row >= len(w.lines) {
+			w.row = 0
+		}
+	case action == "done":
+		w.picking = false
+	}
+}
+
+// export writes the recorded run, or the microcode of the current opcode, as a value change dump
+func (w *Waveform) export() {
+	base := strings.TrimSuffix(config.CLIConfig.RomFile, filepath.Ext(config.CLIConfig.RomFile))
+	var samples []trace.Sample
+	var filename, comment string
+	if w.trace {
+		samples = w.recorder.Samples()
+		filename = base + ".trace.vcd"
+		comment = fmt.Sprintf("%d recorded phase(s)", len(samples))
+	} else if oc, flags := w.current(); oc != nil {
+		samples = trace.OpCodeSamples(oc, flags)
+		filename = fmt.Sprintf("%s.%s.vcd", base, display.HexData(oc.OpCode))
+		comment = fmt.Sprintf("Microcode of %s %s %s, flags %04b", display.HexData(oc.OpCode), oc.Name, instructionSet.AddressModeNames[oc.AddrMode], flags)
+	}
+	if len(samples) == 0 {
+		w.log.Warn("Nothing to export")
+		return
+	}
+	hz := uint64(w.hz())
+	if hz == 0 {
+		hz = 1
+	}
+	if err := trace.ExportVCD(filename, samples, 500000000 / hz, comment); err != nil {
+		w.log.Warnf("VCD export failed: %v", err)
+	} else {
+		w.log.Infof("VCD written to %s", filename)
+	}
+}
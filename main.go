@@ -7,5 +7,7 @@ import (
 
 // go:generate swagger generate spec -o ./swaggerui/swagger-spec.json --scan-models --exclude-deps
 func main() {
-	log.Fatal(cmd.Execute())
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
 }